@@ -1,6 +1,8 @@
 package async
 
 import (
+	"sync"
+
 	lv "github.com/sethpollack/go-live-view/liveview"
 )
 
@@ -13,11 +15,15 @@ const (
 )
 
 type Async[T any] struct {
+	mu    sync.RWMutex
 	value T
 	state State
 	err   error
 }
 
+// New runs fetch in the background. Once it completes the result is stored
+// and an "async:update" Info message is sent to the liveview's mailbox so
+// it re-renders.
 func New[T any](s lv.Socket, fetch func() (T, error)) *Async[T] {
 	a := &Async[T]{
 		state: Loading,
@@ -29,12 +35,17 @@ func New[T any](s lv.Socket, fetch func() (T, error)) *Async[T] {
 
 	go func(s lv.Socket) {
 		result, err := fetch()
+
+		a.mu.Lock()
 		if err != nil {
 			a.state = Failed
 			a.err = err
+		} else {
+			a.value = result
+			a.state = Loaded
 		}
-		a.value = result
-		a.state = Loaded
+		a.mu.Unlock()
+
 		s.PushSelf("async:update", nil)
 	}(s)
 
@@ -42,13 +53,22 @@ func New[T any](s lv.Socket, fetch func() (T, error)) *Async[T] {
 }
 
 func (a *Async[T]) Value() T {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	return a.value
 }
 
 func (a *Async[T]) State() State {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	return a.state
 }
 
 func (a *Async[T]) Error() error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	return a.err
 }
@@ -1,8 +1,19 @@
 package channel
 
+import "fmt"
+
 type Channel interface {
 	Join(Socket, any) error
 	Leave(Socket) error
 	Message(Socket, string, any) error
 	Broadcast(Socket, string, any) error
 }
+
+// ReplyError is an error replied with Response in place of the reason.
+type ReplyError struct {
+	Response map[string]any
+}
+
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("error reply: %v", e.Response)
+}
@@ -1,16 +1,83 @@
 package channel
 
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	ErrQueueFull  = errors.New("outbound queue full")
+	ErrConnClosed = errors.New("connection closed")
+)
+
+const (
+	defaultQueueSize    = 256
+	defaultQueueTimeout = 5 * time.Second
+	defaultFlushTimeout = 5 * time.Second
+)
+
 type Conn interface {
 	ReadMessage() ([]byte, error)
 	WriteMessage([]byte) error
 }
 
+// SlowClientPolicy decides what happens when a connection's outbound queue
+// is full.
+type SlowClientPolicy int
+
+const (
+	// Block waits up to the queue timeout for space, then drops the message.
+	Block SlowClientPolicy = iota
+	// Drop discards the message immediately.
+	Drop
+	// Disconnect closes the connection.
+	Disconnect
+)
+
+// QueueObserver receives outbound queue events, e.g. to export metrics.
+type QueueObserver interface {
+	Enqueued(depth int)
+	Dequeued(depth int)
+	Dropped(depth int)
+}
+
+type queueOptions struct {
+	size     int
+	policy   SlowClientPolicy
+	timeout  time.Duration
+	observer QueueObserver
+
+	// flush is how long Close waits for queued messages to be sent.
+	flush time.Duration
+}
+
 type conn struct {
-	c Conn
+	c    Conn
+	opts queueOptions
+
+	queue    chan []byte
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+
+	closeOnce sync.Once
+	closeErr  error
 }
 
-func newConnection(c Conn) *conn {
-	return &conn{c: c}
+func newConnection(c Conn, opts queueOptions) *conn {
+	t := &conn{
+		c:       c,
+		opts:    opts,
+		queue:   make(chan []byte, opts.size),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+
+	go t.writeLoop()
+
+	return t
 }
 
 func (t *conn) ReadMessage() (*Message, error) {
@@ -21,10 +88,137 @@ func (t *conn) ReadMessage() (*Message, error) {
 	return decode(data)
 }
 
+// WriteMessage encodes m and queues it for the connection's writer.
 func (t *conn) WriteMessage(m *Message) error {
 	data, err := encode(m)
 	if err != nil {
 		return err
 	}
+
+	select {
+	case <-t.done:
+		return ErrConnClosed
+	default:
+	}
+
+	select {
+	case <-t.done:
+		return ErrConnClosed
+	case t.queue <- data:
+		t.enqueued()
+		return nil
+	default:
+	}
+
+	switch t.opts.policy {
+	case Drop:
+		t.dropped()
+		return ErrQueueFull
+	case Disconnect:
+		// the client isn't keeping up, so there's no point flushing.
+		t.dropped()
+		t.stop()
+		t.closeConn()
+		return ErrQueueFull
+	default:
+		timer := time.NewTimer(t.opts.timeout)
+		defer timer.Stop()
+
+		select {
+		case <-t.done:
+			return ErrConnClosed
+		case t.queue <- data:
+			t.enqueued()
+			return nil
+		case <-timer.C:
+			t.dropped()
+			return ErrQueueFull
+		}
+	}
+}
+
+// Close stops taking messages and waits for the writer to send the ones
+// already queued, for up to the flush timeout. It then closes the
+// underlying connection if it supports it, which unblocks any pending
+// reads and writes.
+func (t *conn) Close() error {
+	t.stop()
+
+	timer := time.NewTimer(t.opts.flush)
+	defer timer.Stop()
+
+	select {
+	case <-t.stopped:
+	case <-timer.C:
+	}
+
+	return t.closeConn()
+}
+
+func (t *conn) stop() {
+	t.stopOnce.Do(func() {
+		close(t.done)
+	})
+}
+
+func (t *conn) closeConn() error {
+	t.closeOnce.Do(func() {
+		if c, ok := t.c.(io.Closer); ok {
+			t.closeErr = c.Close()
+		}
+	})
+
+	return t.closeErr
+}
+
+func (t *conn) writeLoop() {
+	defer close(t.stopped)
+
+	for {
+		select {
+		case <-t.done:
+			t.flush()
+			return
+		case data := <-t.queue:
+			if err := t.write(data); err != nil {
+				t.stop()
+				t.closeConn()
+				return
+			}
+		}
+	}
+}
+
+// flush sends what's left in the queue, giving up on the first error.
+func (t *conn) flush() {
+	for {
+		select {
+		case data := <-t.queue:
+			if err := t.write(data); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (t *conn) write(data []byte) error {
+	if t.opts.observer != nil {
+		t.opts.observer.Dequeued(len(t.queue))
+	}
+
 	return t.c.WriteMessage(data)
 }
+
+func (t *conn) enqueued() {
+	if t.opts.observer != nil {
+		t.opts.observer.Enqueued(len(t.queue))
+	}
+}
+
+func (t *conn) dropped() {
+	if t.opts.observer != nil {
+		t.opts.observer.Dropped(len(t.queue))
+	}
+}
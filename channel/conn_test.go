@@ -0,0 +1,210 @@
+package channel
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testConn struct {
+	writing  atomic.Int32
+	overlap  atomic.Bool
+	release  chan struct{}
+	closed   chan struct{}
+	once     sync.Once
+	mu       sync.Mutex
+	messages [][]byte
+}
+
+func newTestConn(blocked bool) *testConn {
+	c := &testConn{
+		release: make(chan struct{}),
+		closed:  make(chan struct{}),
+	}
+	if !blocked {
+		close(c.release)
+	}
+	return c
+}
+
+func (c *testConn) ReadMessage() ([]byte, error) {
+	<-c.closed
+	return nil, ErrConnClosed
+}
+
+func (c *testConn) WriteMessage(data []byte) error {
+	if c.writing.Add(1) > 1 {
+		c.overlap.Store(true)
+	}
+	defer c.writing.Add(-1)
+
+	<-c.release
+
+	c.mu.Lock()
+	c.messages = append(c.messages, data)
+	c.mu.Unlock()
+
+	return nil
+}
+
+func (c *testConn) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	return nil
+}
+
+func (c *testConn) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.messages)
+}
+
+type testObserver struct {
+	maxDepth atomic.Int32
+	dropped  atomic.Int32
+}
+
+func (o *testObserver) Enqueued(depth int) {
+	for {
+		cur := o.maxDepth.Load()
+		if int32(depth) <= cur || o.maxDepth.CompareAndSwap(cur, int32(depth)) {
+			return
+		}
+	}
+}
+
+func (o *testObserver) Dequeued(depth int) {}
+
+func (o *testObserver) Dropped(depth int) {
+	o.dropped.Add(1)
+}
+
+func TestConnSerializesWrites(t *testing.T) {
+	tc := newTestConn(false)
+	c := newConnection(tc, queueOptions{size: 10, policy: Block, timeout: time.Second})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, c.WriteMessage(&Message{Event: "diff"}))
+		}()
+	}
+	wg.Wait()
+
+	assert.Eventually(t, func() bool { return tc.count() == 50 }, time.Second, time.Millisecond)
+	assert.False(t, tc.overlap.Load())
+}
+
+func TestConnSlowClientPolicy(t *testing.T) {
+	tt := []struct {
+		name        string
+		policy      SlowClientPolicy
+		expectErr   error
+		expectClose bool
+	}{
+		{
+			name:      "drop",
+			policy:    Drop,
+			expectErr: ErrQueueFull,
+		},
+		{
+			name:      "block with timeout",
+			policy:    Block,
+			expectErr: ErrQueueFull,
+		},
+		{
+			name:        "disconnect",
+			policy:      Disconnect,
+			expectErr:   ErrQueueFull,
+			expectClose: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			conn := newTestConn(true)
+			defer close(conn.release)
+
+			observer := &testObserver{}
+			c := newConnection(conn, queueOptions{
+				size:     2,
+				policy:   tc.policy,
+				timeout:  10 * time.Millisecond,
+				observer: observer,
+			})
+			defer c.Close()
+
+			// the first message is taken by the blocked writer, the next
+			// two fill the queue.
+			assert.NoError(t, c.WriteMessage(&Message{}))
+			assert.Eventually(t, func() bool { return conn.writing.Load() == 1 }, time.Second, time.Millisecond)
+			assert.NoError(t, c.WriteMessage(&Message{}))
+			assert.NoError(t, c.WriteMessage(&Message{}))
+
+			assert.Equal(t, tc.expectErr, c.WriteMessage(&Message{}))
+			assert.Equal(t, int32(1), observer.dropped.Load())
+			assert.Equal(t, int32(2), observer.maxDepth.Load())
+
+			select {
+			case <-conn.closed:
+				assert.True(t, tc.expectClose)
+				assert.Equal(t, ErrConnClosed, c.WriteMessage(&Message{}))
+			default:
+				assert.False(t, tc.expectClose)
+			}
+		})
+	}
+}
+
+func TestConnCloseFlushes(t *testing.T) {
+	conn := newTestConn(true)
+	c := newConnection(conn, queueOptions{size: 10, policy: Block, timeout: time.Second, flush: time.Second})
+
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, c.WriteMessage(&Message{Event: "diff"}))
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		assert.NoError(t, c.Close())
+		close(closed)
+	}()
+
+	// queued messages are still sent, new ones aren't taken.
+	assert.Eventually(t, func() bool {
+		return c.WriteMessage(&Message{}) == ErrConnClosed
+	}, time.Second, time.Millisecond)
+	close(conn.release)
+
+	<-closed
+	assert.Equal(t, 5, conn.count())
+	assert.True(t, isClosed(conn))
+}
+
+func TestConnCloseDeadline(t *testing.T) {
+	conn := newTestConn(true)
+	defer close(conn.release)
+
+	c := newConnection(conn, queueOptions{size: 10, policy: Block, timeout: time.Second, flush: 10 * time.Millisecond})
+	assert.NoError(t, c.WriteMessage(&Message{}))
+
+	// a writer that never finishes doesn't hold Close up.
+	start := time.Now()
+	assert.NoError(t, c.Close())
+	assert.Less(t, time.Since(start), time.Second)
+	assert.True(t, isClosed(conn))
+}
+
+func isClosed(c *testConn) bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
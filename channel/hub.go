@@ -2,6 +2,8 @@ package channel
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"sync"
 )
 
@@ -9,14 +11,38 @@ type broadcaster interface {
 	Broadcast(msg *Message) error
 }
 
+type hubOption func(*Hub)
+
+// subscriber is a single channel, identified by its topic, on a server.
+type subscriber struct {
+	server broadcaster
+	topic  string
+}
+
 type Hub struct {
 	mu      sync.RWMutex
+	pubsub  PubSub
 	servers map[broadcaster]struct{}
+	topics  map[string]map[subscriber]struct{}
 }
 
-func NewHub() *Hub {
-	return &Hub{
+func NewHub(opts ...hubOption) *Hub {
+	h := &Hub{
 		servers: make(map[broadcaster]struct{}),
+		topics:  make(map[string]map[subscriber]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// WithPubSub shares broadcasts with other hubs through the adapter.
+func WithPubSub(ps PubSub) hubOption {
+	return func(h *Hub) {
+		h.pubsub = ps
 	}
 }
 
@@ -32,26 +58,109 @@ func (sr *Hub) Remove(s broadcaster) {
 	defer sr.mu.Unlock()
 
 	delete(sr.servers, s)
+
+	for topic, subs := range sr.topics {
+		for sub := range subs {
+			if sub.server == s {
+				delete(subs, sub)
+			}
+		}
+		if len(subs) == 0 {
+			delete(sr.topics, topic)
+		}
+	}
 }
 
-func (sr *Hub) WriteMessage(msg *Message) error {
-	// send to all sockets
-	for s := range sr.servers {
-		err := s.Broadcast(msg)
-		if err != nil {
-			return err
+// Subscribe delivers messages published on topic to the channel
+// joined on channelTopic.
+func (sr *Hub) Subscribe(topic string, s broadcaster, channelTopic string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	subs, ok := sr.topics[topic]
+	if !ok {
+		subs = make(map[subscriber]struct{})
+		sr.topics[topic] = subs
+	}
+
+	subs[subscriber{server: s, topic: channelTopic}] = struct{}{}
+}
+
+func (sr *Hub) Unsubscribe(topic string, s broadcaster, channelTopic string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	subs, ok := sr.topics[topic]
+	if !ok {
+		return
+	}
+
+	delete(subs, subscriber{server: s, topic: channelTopic})
+
+	if len(subs) == 0 {
+		delete(sr.topics, topic)
+	}
+}
+
+// UnsubscribeAll removes every subscription held by the channel.
+func (sr *Hub) UnsubscribeAll(s broadcaster, channelTopic string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	sub := subscriber{server: s, topic: channelTopic}
+
+	for topic, subs := range sr.topics {
+		delete(subs, sub)
+		if len(subs) == 0 {
+			delete(sr.topics, topic)
 		}
 	}
-	// sr.Broadcast(msg) // TODO: send to pubsub
-	return nil
+}
+
+// WriteMessage publishes msg to the subscribers of msg.Topic.
+func (sr *Hub) WriteMessage(msg *Message) error {
+	if sr.pubsub != nil {
+		return sr.pubsub.Publish(msg)
+	}
+
+	return sr.deliver(msg)
 }
 
 func (sr *Hub) Listen(ctx context.Context) {
-	// TODO add listener for pubsub
-	for {
-		select {
-		case <-ctx.Done():
-			return
+	if sr.pubsub == nil {
+		<-ctx.Done()
+		return
+	}
+
+	err := sr.pubsub.Listen(ctx, func(msg *Message) {
+		if err := sr.deliver(msg); err != nil {
+			fmt.Println(err)
+		}
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Println(err)
+	}
+}
+
+func (sr *Hub) deliver(msg *Message) error {
+	sr.mu.RLock()
+	subs := make([]subscriber, 0, len(sr.topics[msg.Topic]))
+	for sub := range sr.topics[msg.Topic] {
+		subs = append(subs, sub)
+	}
+	sr.mu.RUnlock()
+
+	var errs []error
+	for _, sub := range subs {
+		err := sub.server.Broadcast(&Message{
+			Topic:   sub.topic,
+			Event:   msg.Event,
+			Payload: msg.Payload,
+		})
+		if err != nil {
+			errs = append(errs, err)
 		}
 	}
+
+	return errors.Join(errs...)
 }
@@ -0,0 +1,121 @@
+package channel
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBroadcaster struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+func (b *testBroadcaster) Broadcast(msg *Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.messages = append(b.messages, msg)
+	return nil
+}
+
+func (b *testBroadcaster) topics() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	topics := []string{}
+	for _, msg := range b.messages {
+		topics = append(topics, msg.Topic)
+	}
+	return topics
+}
+
+func TestHub(t *testing.T) {
+	tt := []struct {
+		name      string
+		setup     func(h *Hub, a, b *testBroadcaster)
+		expectA   []string
+		expectB   []string
+		publishTo string
+	}{
+		{
+			name:      "no subscribers",
+			setup:     func(h *Hub, a, b *testBroadcaster) {},
+			publishTo: "room:1",
+			expectA:   []string{},
+			expectB:   []string{},
+		},
+		{
+			name: "only subscribers receive",
+			setup: func(h *Hub, a, b *testBroadcaster) {
+				h.Subscribe("room:1", a, "lv:a")
+				h.Subscribe("room:2", b, "lv:b")
+			},
+			publishTo: "room:1",
+			expectA:   []string{"lv:a"},
+			expectB:   []string{},
+		},
+		{
+			name: "delivered to every subscribed channel",
+			setup: func(h *Hub, a, b *testBroadcaster) {
+				h.Subscribe("room:1", a, "lv:a1")
+				h.Subscribe("room:1", a, "lv:a2")
+				h.Subscribe("room:1", b, "lv:b")
+			},
+			publishTo: "room:1",
+			expectA:   []string{"lv:a1", "lv:a2"},
+			expectB:   []string{"lv:b"},
+		},
+		{
+			name: "unsubscribe",
+			setup: func(h *Hub, a, b *testBroadcaster) {
+				h.Subscribe("room:1", a, "lv:a")
+				h.Subscribe("room:1", b, "lv:b")
+				h.Unsubscribe("room:1", b, "lv:b")
+			},
+			publishTo: "room:1",
+			expectA:   []string{"lv:a"},
+			expectB:   []string{},
+		},
+		{
+			name: "unsubscribe all for channel",
+			setup: func(h *Hub, a, b *testBroadcaster) {
+				h.Subscribe("room:1", a, "lv:a1")
+				h.Subscribe("room:1", a, "lv:a2")
+				h.UnsubscribeAll(a, "lv:a1")
+			},
+			publishTo: "room:1",
+			expectA:   []string{"lv:a2"},
+			expectB:   []string{},
+		},
+		{
+			name: "removed servers are unsubscribed",
+			setup: func(h *Hub, a, b *testBroadcaster) {
+				h.Add(a)
+				h.Add(b)
+				h.Subscribe("room:1", a, "lv:a")
+				h.Subscribe("room:1", b, "lv:b")
+				h.Remove(a)
+			},
+			publishTo: "room:1",
+			expectA:   []string{},
+			expectB:   []string{"lv:b"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHub()
+			a, b := &testBroadcaster{}, &testBroadcaster{}
+
+			tc.setup(h, a, b)
+
+			err := h.WriteMessage(&Message{Topic: tc.publishTo, Event: "event", Payload: "hello"})
+			assert.NoError(t, err)
+
+			assert.ElementsMatch(t, tc.expectA, a.topics())
+			assert.ElementsMatch(t, tc.expectB, b.topics())
+		})
+	}
+}
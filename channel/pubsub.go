@@ -0,0 +1,10 @@
+package channel
+
+import "context"
+
+// PubSub distributes published messages to every hub sharing the
+// adapter, including the hub that published them.
+type PubSub interface {
+	Publish(*Message) error
+	Listen(context.Context, func(*Message)) error
+}
@@ -0,0 +1,349 @@
+package gossip
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sethpollack/go-live-view/channel"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 5 * time.Second
+	nonceSize    = 16
+
+	// messages for a peer that can't keep up are dropped past queueSize.
+	queueSize = 1024
+)
+
+var _ channel.PubSub = (*Node)(nil)
+
+// New starts a node listening on address and fanning published messages
+// out to peers. network is any stream network accepted by net.Listen,
+// such as "tcp" or "unix". Every node should list every other node as a
+// peer; messages are not relayed.
+//
+// Nodes only accept messages signed with secret, which every node must
+// share. Messages aren't encrypted, so nodes should still only listen on a
+// private network.
+func New(network, address, secret string, peers ...string) (*Node, error) {
+	if secret == "" {
+		return nil, errors.New("gossip: a secret is required")
+	}
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		network:   network,
+		key:       []byte(secret),
+		ln:        ln,
+		peers:     make(map[string]*peer),
+		listeners: make(map[int]func(*channel.Message)),
+	}
+
+	for _, addr := range peers {
+		n.AddPeer(addr)
+	}
+
+	go n.accept()
+
+	return n, nil
+}
+
+// Node is a PubSub shared with other processes over the network.
+type Node struct {
+	network string
+	key     []byte
+	ln      net.Listener
+
+	pmu   sync.Mutex
+	peers map[string]*peer
+
+	lmu       sync.RWMutex
+	next      int
+	listeners map[int]func(*channel.Message)
+}
+
+// frame is a message signed for the connection it's sent on.
+type frame struct {
+	Message json.RawMessage `json:"m"`
+	MAC     []byte          `json:"h"`
+}
+
+// peer sends messages to another node from its own goroutine, so a slow or
+// unreachable node never blocks publishing.
+type peer struct {
+	node    *Node
+	addr    string
+	queue   chan *channel.Message
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	conn  net.Conn
+	enc   *json.Encoder
+	nonce []byte
+}
+
+// Addr returns the address the node is listening on.
+func (n *Node) Addr() net.Addr {
+	return n.ln.Addr()
+}
+
+func (n *Node) AddPeer(address string) {
+	n.pmu.Lock()
+	defer n.pmu.Unlock()
+
+	if _, ok := n.peers[address]; ok {
+		return
+	}
+
+	p := &peer{
+		node:    n,
+		addr:    address,
+		queue:   make(chan *channel.Message, queueSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	n.peers[address] = p
+
+	go p.run()
+}
+
+func (n *Node) RemovePeer(address string) {
+	n.pmu.Lock()
+	p, ok := n.peers[address]
+	delete(n.peers, address)
+	n.pmu.Unlock()
+
+	if ok {
+		p.close()
+	}
+}
+
+func (n *Node) Publish(msg *channel.Message) error {
+	n.deliver(msg)
+
+	n.pmu.Lock()
+	defer n.pmu.Unlock()
+
+	for _, p := range n.peers {
+		select {
+		case p.queue <- msg:
+		default:
+			fmt.Println(fmt.Errorf("publishing to %s: queue full, dropping message", p.addr))
+		}
+	}
+
+	return nil
+}
+
+func (n *Node) Listen(ctx context.Context, handle func(*channel.Message)) error {
+	n.lmu.Lock()
+	id := n.next
+	n.next++
+	n.listeners[id] = handle
+	n.lmu.Unlock()
+
+	<-ctx.Done()
+
+	n.lmu.Lock()
+	delete(n.listeners, id)
+	n.lmu.Unlock()
+
+	return ctx.Err()
+}
+
+// Close stops accepting messages and disconnects from all peers, once the
+// messages queued for them are sent.
+func (n *Node) Close() error {
+	err := n.ln.Close()
+
+	n.pmu.Lock()
+	peers := n.peers
+	n.peers = make(map[string]*peer)
+	n.pmu.Unlock()
+
+	for _, p := range peers {
+		p.close()
+	}
+	for _, p := range peers {
+		<-p.stopped
+	}
+
+	return err
+}
+
+func (n *Node) accept() {
+	for {
+		conn, err := n.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				fmt.Println(err)
+			}
+			return
+		}
+
+		go n.read(conn)
+	}
+}
+
+// read delivers messages from a peer. The peer signs each one with the
+// nonce sent when it connected, so frames can't be replayed on another
+// connection. The connection is dropped on the first bad signature.
+func (n *Node) read(conn net.Conn) {
+	defer conn.Close()
+
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return
+	}
+
+	conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := conn.Write(nonce); err != nil {
+		return
+	}
+
+	dec := json.NewDecoder(conn)
+	for {
+		f := frame{}
+		if err := dec.Decode(&f); err != nil {
+			return
+		}
+
+		if !hmac.Equal(f.MAC, n.sign(nonce, f.Message)) {
+			fmt.Println(fmt.Errorf("gossip: bad signature from %s", conn.RemoteAddr()))
+			return
+		}
+
+		msg := &channel.Message{}
+		if err := json.Unmarshal(f.Message, msg); err != nil {
+			return
+		}
+
+		n.deliver(msg)
+	}
+}
+
+func (n *Node) sign(nonce, msg []byte) []byte {
+	mac := hmac.New(sha256.New, n.key)
+	mac.Write(nonce)
+	mac.Write(msg)
+	return mac.Sum(nil)
+}
+
+func (n *Node) deliver(msg *channel.Message) {
+	n.lmu.RLock()
+	listeners := make([]func(*channel.Message), 0, len(n.listeners))
+	for _, l := range n.listeners {
+		listeners = append(listeners, l)
+	}
+	n.lmu.RUnlock()
+
+	for _, l := range listeners {
+		l(msg)
+	}
+}
+
+func (p *peer) run() {
+	defer close(p.stopped)
+	defer p.disconnect()
+
+	for {
+		select {
+		case <-p.done:
+			p.flush()
+			return
+		case msg := <-p.queue:
+			if err := p.send(msg); err != nil {
+				fmt.Println(fmt.Errorf("publishing to %s: %w", p.addr, err))
+			}
+		}
+	}
+}
+
+// flush sends what's left in the queue, giving up on the first error.
+func (p *peer) flush() {
+	for {
+		select {
+		case msg := <-p.queue:
+			if err := p.send(msg); err != nil {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *peer) send(msg *channel.Message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	// retry once with a fresh connection in case the old one went stale.
+	for i := 0; i < 2; i++ {
+		if p.conn == nil {
+			if err = p.connect(); err != nil {
+				return err
+			}
+		}
+
+		p.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		err = p.enc.Encode(frame{Message: b, MAC: p.node.sign(p.nonce, b)})
+		if err == nil {
+			return nil
+		}
+
+		p.disconnect()
+	}
+
+	return err
+}
+
+func (p *peer) connect() error {
+	conn, err := net.DialTimeout(p.node.network, p.addr, dialTimeout)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, nonceSize)
+	conn.SetReadDeadline(time.Now().Add(dialTimeout))
+	if _, err := io.ReadFull(conn, nonce); err != nil {
+		conn.Close()
+		return err
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	p.conn = conn
+	p.enc = json.NewEncoder(conn)
+	p.nonce = nonce
+
+	return nil
+}
+
+func (p *peer) disconnect() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn = nil
+	}
+}
+
+func (p *peer) close() {
+	p.once.Do(func() {
+		close(p.done)
+	})
+}
@@ -0,0 +1,251 @@
+package gossip
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sethpollack/go-live-view/channel"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBroadcaster struct {
+	received chan *channel.Message
+}
+
+func (b *testBroadcaster) Broadcast(msg *channel.Message) error {
+	b.received <- msg
+	return nil
+}
+
+func TestNodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	nodes := []*Node{}
+	subs := []*testBroadcaster{}
+
+	for i := 0; i < 3; i++ {
+		g, err := New("tcp", "127.0.0.1:0", "secret")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer g.Close()
+
+		h := channel.NewHub(channel.WithPubSub(g))
+		go h.Listen(ctx)
+
+		sub := &testBroadcaster{received: make(chan *channel.Message, 10)}
+		h.Subscribe("room:1", sub, fmt.Sprintf("lv:%d", i))
+
+		nodes = append(nodes, g)
+		subs = append(subs, sub)
+	}
+
+	for _, a := range nodes {
+		for _, b := range nodes {
+			if a != b {
+				a.AddPeer(b.Addr().String())
+			}
+		}
+	}
+
+	waitForListeners(t, nodes...)
+
+	err := nodes[0].Publish(&channel.Message{
+		Topic:   "room:1",
+		Event:   "event",
+		Payload: map[string]any{"msg": "hello"},
+	})
+	assert.NoError(t, err)
+
+	for i, sub := range subs {
+		msg := receive(t, sub.received)
+		assert.Equal(t, fmt.Sprintf("lv:%d", i), msg.Topic)
+		assert.Equal(t, map[string]any{"msg": "hello"}, msg.Payload)
+	}
+}
+
+// TestProcesses runs a second node in a child process and exchanges a
+// message in each direction over unix sockets.
+func TestProcesses(t *testing.T) {
+	dir := t.TempDir()
+	parentAddr := filepath.Join(dir, "parent.sock")
+	childAddr := filepath.Join(dir, "child.sock")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	g, err := New("unix", parentAddr, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	h := channel.NewHub(channel.WithPubSub(g))
+	go h.Listen(ctx)
+
+	sub := &testBroadcaster{received: make(chan *channel.Message, 1)}
+	h.Subscribe("pong", sub, "lv:parent")
+	waitForListeners(t, g)
+
+	cmd := exec.CommandContext(ctx, os.Args[0], "-test.run=^TestHelperProcess$")
+	cmd.Env = append(os.Environ(),
+		"GOSSIP_HELPER=1",
+		"GOSSIP_ADDR="+childAddr,
+		"GOSSIP_PEER="+parentAddr,
+	)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Wait()
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if scanner.Text() == "ready" {
+			break
+		}
+	}
+
+	g.AddPeer(childAddr)
+
+	err = g.Publish(&channel.Message{Topic: "ping", Event: "event", Payload: "ping"})
+	assert.NoError(t, err)
+
+	msg := receive(t, sub.received)
+	assert.Equal(t, "lv:parent", msg.Topic)
+	assert.Equal(t, "pong", msg.Payload)
+}
+
+func TestSecret(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := New("tcp", "127.0.0.1:0", "")
+	assert.EqualError(t, err, "gossip: a secret is required")
+
+	g, err := New("tcp", "127.0.0.1:0", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	received := make(chan *channel.Message, 10)
+	go g.Listen(ctx, func(msg *channel.Message) { received <- msg })
+	waitForListeners(t, g)
+
+	// a node with another secret
+	other, err := New("tcp", "127.0.0.1:0", "other", g.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	assert.NoError(t, other.Publish(&channel.Message{Topic: "room:1", Payload: "forged"}))
+
+	// an unsigned frame
+	conn, err := net.Dial("tcp", g.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(`{"m":{"topic":"room:1","payload":"injected"},"h":""}` + "\n"))
+	assert.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		t.Fatalf("delivered %v", msg.Payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestUnreachablePeer(t *testing.T) {
+	g, err := New("tcp", "127.0.0.1:0", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	// a listener that never accepts keeps the dial hanging.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	g.AddPeer(ln.Addr().String())
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < queueSize+10; i++ {
+			g.Publish(&channel.Message{Topic: "room:1", Payload: i})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publish blocked on an unreachable peer")
+	}
+}
+
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("GOSSIP_HELPER") != "1" {
+		t.Skip("helper process")
+	}
+
+	g, err := New("unix", os.Getenv("GOSSIP_ADDR"), "secret", os.Getenv("GOSSIP_PEER"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go g.Listen(ctx, func(msg *channel.Message) {
+		if msg.Topic == "ping" {
+			g.Publish(&channel.Message{Topic: "pong", Event: "event", Payload: "pong"})
+			cancel()
+		}
+	})
+	waitForListeners(t, g)
+
+	fmt.Println("ready")
+
+	<-ctx.Done()
+}
+
+func waitForListeners(t *testing.T, nodes ...*Node) {
+	t.Helper()
+
+	for _, g := range nodes {
+		assert.Eventually(t, func() bool {
+			g.lmu.RLock()
+			defer g.lmu.RUnlock()
+			return len(g.listeners) > 0
+		}, time.Second, time.Millisecond)
+	}
+}
+
+func receive(t *testing.T, c chan *channel.Message) *channel.Message {
+	t.Helper()
+
+	select {
+	case msg := <-c:
+		return msg
+	case <-time.After(2 * time.Second):
+		t.Fatal("message not delivered")
+		return nil
+	}
+}
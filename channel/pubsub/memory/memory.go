@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sethpollack/go-live-view/channel"
+)
+
+var _ channel.PubSub = (*memory)(nil)
+
+// New returns a PubSub shared by hubs in the same process.
+func New() channel.PubSub {
+	return &memory{
+		listeners: make(map[int]func(*channel.Message)),
+	}
+}
+
+type memory struct {
+	mu        sync.RWMutex
+	next      int
+	listeners map[int]func(*channel.Message)
+}
+
+func (m *memory) Publish(msg *channel.Message) error {
+	m.mu.RLock()
+	listeners := make([]func(*channel.Message), 0, len(m.listeners))
+	for _, l := range m.listeners {
+		listeners = append(listeners, l)
+	}
+	m.mu.RUnlock()
+
+	for _, l := range listeners {
+		l(msg)
+	}
+
+	return nil
+}
+
+func (m *memory) Listen(ctx context.Context, handle func(*channel.Message)) error {
+	m.mu.Lock()
+	id := m.next
+	m.next++
+	m.listeners[id] = handle
+	m.mu.Unlock()
+
+	<-ctx.Done()
+
+	m.mu.Lock()
+	delete(m.listeners, id)
+	m.mu.Unlock()
+
+	return ctx.Err()
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sethpollack/go-live-view/channel"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBroadcaster struct {
+	received chan *channel.Message
+}
+
+func (b *testBroadcaster) Broadcast(msg *channel.Message) error {
+	b.received <- msg
+	return nil
+}
+
+func TestSharedHubs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps := New()
+
+	a := channel.NewHub(channel.WithPubSub(ps))
+	b := channel.NewHub(channel.WithPubSub(ps))
+
+	var wg sync.WaitGroup
+	for _, h := range []*channel.Hub{a, b} {
+		wg.Add(1)
+		go func(h *channel.Hub) {
+			defer wg.Done()
+			h.Listen(ctx)
+		}(h)
+	}
+
+	sub := &testBroadcaster{received: make(chan *channel.Message, 1)}
+	b.Subscribe("room:1", sub, "lv:b")
+
+	// wait for both hubs to register with the adapter
+	assert.Eventually(t, func() bool {
+		m := ps.(*memory)
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return len(m.listeners) == 2
+	}, time.Second, time.Millisecond)
+
+	payload := struct{ Name string }{"go value"}
+	err := a.WriteMessage(&channel.Message{Topic: "room:1", Event: "event", Payload: payload})
+	assert.NoError(t, err)
+
+	select {
+	case msg := <-sub.received:
+		assert.Equal(t, "lv:b", msg.Topic)
+		assert.Equal(t, payload, msg.Payload)
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	cancel()
+	wg.Wait()
+}
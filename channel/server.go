@@ -2,26 +2,70 @@ package channel
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"time"
+
+	"github.com/rs/xid"
 )
 
+type ServerOption func(*queueOptions)
+
+// WithQueueSize sets how many outbound messages may be buffered per
+// connection.
+func WithQueueSize(size int) ServerOption {
+	return func(o *queueOptions) {
+		o.size = size
+	}
+}
+
+// WithSlowClientPolicy sets what happens when the outbound queue is full.
+// timeout is only used by the Block policy.
+func WithSlowClientPolicy(policy SlowClientPolicy, timeout time.Duration) ServerOption {
+	return func(o *queueOptions) {
+		o.policy = policy
+		o.timeout = timeout
+	}
+}
+
+func WithQueueObserver(observer QueueObserver) ServerOption {
+	return func(o *queueOptions) {
+		o.observer = observer
+	}
+}
+
 type server struct {
 	mu sync.RWMutex
 
+	id       string
 	h        *Hub
 	c        *conn
 	matchers map[string]func() Channel
 	channels map[string]Channel
+	closers  map[string][]func()
 }
 
-func NewServer(c Conn, h *Hub) *server {
+func NewServer(c Conn, h *Hub, opts ...ServerOption) *server {
+	qOpts := queueOptions{
+		size:    defaultQueueSize,
+		policy:  Block,
+		timeout: defaultQueueTimeout,
+		flush:   defaultFlushTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&qOpts)
+	}
+
 	return &server{
+		id:       xid.New().String(),
 		h:        h,
-		c:        newConnection(c),
+		c:        newConnection(c, qOpts),
 		matchers: make(map[string]func() Channel),
 		channels: make(map[string]Channel),
+		closers:  make(map[string][]func()),
 	}
 }
 
@@ -36,6 +80,15 @@ func (s *server) Close(topic string) {
 	s.deleteChannel(topic)
 }
 
+// OnClose registers fn to run once the channel on topic has been closed,
+// either by the client leaving or by the connection going away.
+func (s *server) OnClose(topic string, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closers[topic] = append(s.closers[topic], fn)
+}
+
 func (s *server) Broadcast(msg *Message) error {
 	mChan, err := s.getChannel(msg.Topic)
 	if err != nil {
@@ -55,11 +108,34 @@ func (s *server) PushBroadcast(msg *Message) error {
 	return s.h.WriteMessage(msg)
 }
 
+func (s *server) Subscribe(topic, channelTopic string) error {
+	if s.h == nil {
+		return fmt.Errorf("no server available")
+	}
+
+	s.h.Subscribe(topic, s, channelTopic)
+
+	return nil
+}
+
+func (s *server) Unsubscribe(topic, channelTopic string) error {
+	if s.h == nil {
+		return fmt.Errorf("no server available")
+	}
+
+	s.h.Unsubscribe(topic, s, channelTopic)
+
+	return nil
+}
+
 func (s *server) Push(msg *Message) error {
 	return s.c.WriteMessage(msg)
 }
 
 func (s *server) Listen(ctx context.Context) {
+	defer s.c.Close()
+	defer s.closeChannels()
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -158,16 +234,23 @@ func (s *server) handleMessage(msg *Message) error {
 }
 
 func (s *server) handleError(msg *Message, err error) {
+	response := map[string]any{
+		"reason": err.Error(),
+	}
+
+	var reply *ReplyError
+	if errors.As(err, &reply) {
+		response = reply.Response
+	}
+
 	pushErr := s.Push(&Message{
 		JoinRef: msg.JoinRef,
 		Ref:     msg.Ref,
 		Topic:   msg.Topic,
 		Event:   "phx_reply",
 		Payload: map[string]any{
-			"status": "error",
-			"response": map[string]any{
-				"reason": err.Error(),
-			},
+			"status":   "error",
+			"response": response,
 		},
 	})
 	if pushErr != nil {
@@ -206,9 +289,36 @@ func (s *server) setChannel(topic string, c Channel) {
 
 func (s *server) deleteChannel(topic string) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	delete(s.channels, topic)
+	closers := s.closers[topic]
+	delete(s.closers, topic)
+	s.mu.Unlock()
+
+	if s.h != nil {
+		s.h.UnsubscribeAll(s, topic)
+	}
+
+	for _, fn := range closers {
+		fn()
+	}
+}
+
+func (s *server) closeChannels() {
+	s.mu.RLock()
+	topics := make([]string, 0, len(s.channels)+len(s.closers))
+	for topic := range s.channels {
+		topics = append(topics, topic)
+	}
+	for topic := range s.closers {
+		if _, ok := s.channels[topic]; !ok {
+			topics = append(topics, topic)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, topic := range topics {
+		s.deleteChannel(topic)
+	}
 }
 
 func match(pattern, path string) bool {
@@ -1,9 +1,13 @@
 package channel
 
 type Socket interface {
+	ID() string
 	Push(string, any) error
-	PushBroadcast(string, any) error
+	PushBroadcast(string, string, any) error
 	PushSelf(string, any) error
+	Subscribe(string) error
+	Unsubscribe(string) error
+	OnClose(func())
 	Close() error
 }
 
@@ -24,6 +28,16 @@ func NewSocket(s *server, msg *Message) *socket {
 	}
 }
 
+// ID uniquely identifies the channel across connections.
+func (s *socket) ID() string {
+	return s.server.id + ":" + s.topic
+}
+
+// OnClose registers fn to run once the channel has been closed.
+func (s *socket) OnClose(fn func()) {
+	s.server.OnClose(s.topic, fn)
+}
+
 func (s *socket) Push(event string, payload any) error {
 	if payload == nil {
 		payload = map[string]any{}
@@ -84,11 +98,21 @@ func (s *socket) PushSelf(event string, payload any) error {
 	})
 }
 
-func (s *socket) PushBroadcast(event string, payload any) error {
+// PushBroadcast publishes payload to every channel subscribed to topic.
+func (s *socket) PushBroadcast(topic string, event string, payload any) error {
 	return s.server.PushBroadcast(&Message{
-		JoinRef: s.joinRef,
-		Topic:   s.topic,
+		Topic:   topic,
 		Event:   event,
 		Payload: payload,
 	})
 }
+
+// Subscribe delivers broadcasts published on topic to this channel.
+func (s *socket) Subscribe(topic string) error {
+	return s.server.Subscribe(topic, s.topic)
+}
+
+// Unsubscribe stops delivering broadcasts published on topic.
+func (s *socket) Unsubscribe(topic string) error {
+	return s.server.Unsubscribe(topic, s.topic)
+}
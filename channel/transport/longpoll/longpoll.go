@@ -1,21 +1,83 @@
 package longpoll
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/sethpollack/go-live-view/channel"
 )
 
 var _ channel.Transport = (*lpTransport)(nil)
 
-func New(path string) channel.Transport {
-	return &lpTransport{
-		path: path,
+var errSessionClosed = errors.New("longpoll session closed")
+
+const (
+	defaultPollTimeout = 10 * time.Second
+	defaultIdleTimeout = 30 * time.Second
+	defaultMaxBodySize = 8 * 1024 * 1024
+
+	// outboxSize is how many messages wait for the next poll. Once it's
+	// full, writes block and the connection's queue applies its slow
+	// client policy.
+	outboxSize = 64
+)
+
+type lpOption func(*lpTransport)
+
+type lpTransport struct {
+	path        string
+	pollTimeout time.Duration
+	idleTimeout time.Duration
+	maxBodySize int64
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+func New(path string, opts ...lpOption) channel.Transport {
+	t := &lpTransport{
+		path:        path,
+		pollTimeout: defaultPollTimeout,
+		idleTimeout: defaultIdleTimeout,
+		maxBodySize: defaultMaxBodySize,
+		sessions:    make(map[string]*session),
+	}
+
+	for _, opt := range opts {
+		opt(t)
 	}
+
+	return t
 }
 
-type lpTransport struct {
-	path string
+// WithPollTimeout sets how long a GET is held open waiting for messages.
+func WithPollTimeout(d time.Duration) lpOption {
+	return func(t *lpTransport) {
+		t.pollTimeout = d
+	}
+}
+
+// WithIdleTimeout sets how long a session survives without any requests.
+func WithIdleTimeout(d time.Duration) lpOption {
+	return func(t *lpTransport) {
+		t.idleTimeout = d
+	}
+}
+
+// WithMaxBodySize limits the size of a POSTed batch.
+func WithMaxBodySize(n int64) lpOption {
+	return func(t *lpTransport) {
+		t.maxBodySize = n
+	}
 }
 
 func (l *lpTransport) Path() string {
@@ -23,5 +85,302 @@ func (l *lpTransport) Path() string {
 }
 
 func (t *lpTransport) Serve(handle func(channel.Conn), w http.ResponseWriter, r *http.Request) {
-	panic("not implemented")
+	switch r.Method {
+	case http.MethodGet:
+		t.poll(handle, w, r)
+	case http.MethodPost:
+		t.publish(w, r)
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (t *lpTransport) poll(handle func(channel.Conn), w http.ResponseWriter, r *http.Request) {
+	sess := t.getSession(r.URL.Query().Get("token"))
+	if sess == nil {
+		sess = t.newSession()
+		go func() {
+			defer t.closeSession(sess)
+			handle(sess)
+		}()
+
+		writeStatus(w, http.StatusGone, sess.token, nil)
+		return
+	}
+
+	sess.acquire()
+	defer sess.release()
+
+	messages, ok := sess.wait(r.Context(), t.pollTimeout)
+	if !ok {
+		writeStatus(w, http.StatusGone, "", nil)
+		return
+	}
+
+	if len(messages) == 0 {
+		writeStatus(w, http.StatusNoContent, sess.token, nil)
+		return
+	}
+
+	writeStatus(w, http.StatusOK, sess.token, messages)
+}
+
+func (t *lpTransport) publish(w http.ResponseWriter, r *http.Request) {
+	sess := t.getSession(r.URL.Query().Get("token"))
+	if sess == nil {
+		writeStatus(w, http.StatusGone, "", nil)
+		return
+	}
+
+	sess.acquire()
+	defer sess.release()
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, t.maxBodySize))
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, "", nil)
+		return
+	}
+
+	for _, msg := range splitBatch(r.Header.Get("Content-Type"), body) {
+		data, err := decodeFrame(msg)
+		if err != nil {
+			writeStatus(w, http.StatusBadRequest, "", nil)
+			return
+		}
+
+		if !sess.dispatch(data) {
+			writeStatus(w, http.StatusGone, "", nil)
+			return
+		}
+	}
+
+	writeStatus(w, http.StatusOK, "", nil)
+}
+
+func (t *lpTransport) newSession() *session {
+	sess := newSession(newToken())
+
+	sess.timer = time.AfterFunc(t.idleTimeout, func() {
+		t.closeSession(sess)
+	})
+	sess.idleTimeout = t.idleTimeout
+
+	t.mu.Lock()
+	t.sessions[sess.token] = sess
+	t.mu.Unlock()
+
+	return sess
+}
+
+func (t *lpTransport) getSession(token string) *session {
+	if token == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.sessions[token]
+}
+
+func (t *lpTransport) closeSession(sess *session) {
+	t.mu.Lock()
+	delete(t.sessions, sess.token)
+	t.mu.Unlock()
+
+	sess.close()
+}
+
+var _ channel.Conn = (*session)(nil)
+
+type session struct {
+	token string
+
+	in   chan []byte
+	out  chan string
+	done chan struct{}
+
+	mu          sync.Mutex
+	active      int
+	timer       *time.Timer
+	idleTimeout time.Duration
+	closeOnce   sync.Once
+}
+
+func newSession(token string) *session {
+	return &session{
+		token: token,
+		in:    make(chan []byte),
+		out:   make(chan string, outboxSize),
+		done:  make(chan struct{}),
+	}
+}
+
+func (s *session) ReadMessage() ([]byte, error) {
+	select {
+	case data := <-s.in:
+		return data, nil
+	case <-s.done:
+		return nil, errSessionClosed
+	}
+}
+
+// WriteMessage waits for room in the outbox, so a client that stops
+// polling backs up the connection's queue.
+func (s *session) WriteMessage(data []byte) error {
+	select {
+	case <-s.done:
+		return errSessionClosed
+	default:
+	}
+
+	select {
+	case s.out <- string(data):
+		return nil
+	case <-s.done:
+		return errSessionClosed
+	}
+}
+
+func (s *session) Close() error {
+	s.close()
+	return nil
+}
+
+func (s *session) dispatch(data []byte) bool {
+	select {
+	case s.in <- data:
+		return true
+	case <-s.done:
+		return false
+	}
+}
+
+func (s *session) wait(ctx context.Context, timeout time.Duration) ([]string, bool) {
+	if messages := s.drain(); len(messages) > 0 {
+		return messages, true
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case msg := <-s.out:
+		return append([]string{msg}, s.drain()...), true
+	case <-timer.C:
+		return nil, true
+	case <-ctx.Done():
+		return nil, true
+	case <-s.done:
+		return nil, false
+	}
+}
+
+// drain takes the messages waiting in the outbox.
+func (s *session) drain() []string {
+	var messages []string
+	for {
+		select {
+		case msg := <-s.out:
+			messages = append(messages, msg)
+		default:
+			return messages
+		}
+	}
+}
+
+// acquire pauses the idle timer while a request is in flight.
+func (s *session) acquire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active++
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// release restarts the idle timer once the last request has finished.
+func (s *session) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.active--
+	if s.active == 0 && s.timer != nil {
+		s.timer.Reset(s.idleTimeout)
+	}
+}
+
+func (s *session) close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		if s.timer != nil {
+			s.timer.Stop()
+		}
+		s.mu.Unlock()
+
+		close(s.done)
+	})
+}
+
+func splitBatch(contentType string, body []byte) []string {
+	if !strings.HasPrefix(contentType, "application/x-ndjson") {
+		return []string{string(body)}
+	}
+
+	messages := []string{}
+	for _, line := range strings.Split(string(body), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if line == "" {
+			continue
+		}
+		messages = append(messages, line)
+	}
+
+	return messages
+}
+
+// decodeFrame converts a posted message into a frame. Text frames are JSON
+// arrays; binary frames (upload chunks) are sent base64 encoded by the client.
+func decodeFrame(msg string) ([]byte, error) {
+	if strings.HasPrefix(msg, "[") {
+		return []byte(msg), nil
+	}
+
+	return base64.StdEncoding.DecodeString(msg)
+}
+
+func writeStatus(w http.ResponseWriter, status int, token string, messages []string) {
+	resp := map[string]any{
+		"status": status,
+	}
+
+	if token != "" {
+		resp["token"] = token
+	}
+
+	if messages != nil {
+		resp["messages"] = messages
+	}
+
+	b, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
 }
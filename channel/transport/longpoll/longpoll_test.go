@@ -0,0 +1,162 @@
+package longpoll
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sethpollack/go-live-view/channel"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pollResponse struct {
+	Status   int      `json:"status"`
+	Token    string   `json:"token"`
+	Messages []string `json:"messages"`
+}
+
+func TestLongPoll(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tr := New("/live/longpoll", WithPollTimeout(50*time.Millisecond))
+	handle := func(c channel.Conn) {
+		channel.NewServer(c, nil).Listen(ctx)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tr.Serve(handle, w, r)
+	}))
+	defer srv.Close()
+
+	resp := get(t, srv.URL+"?token=null")
+	assert.Equal(t, http.StatusGone, resp.Status)
+	assert.NotEmpty(t, resp.Token)
+
+	token := resp.Token
+
+	resp = get(t, srv.URL+"?token="+token)
+	assert.Equal(t, http.StatusNoContent, resp.Status)
+
+	resp = post(t, srv.URL+"?token="+token, strings.Join([]string{
+		`[null,"1","phoenix","heartbeat",{}]`,
+		`[null,"2","phoenix","heartbeat",{}]`,
+	}, "\n"))
+	assert.Equal(t, http.StatusOK, resp.Status)
+
+	messages := []string{}
+	for len(messages) < 2 {
+		resp = get(t, srv.URL+"?token="+token)
+		messages = append(messages, resp.Messages...)
+	}
+
+	assert.JSONEq(t, `["","1","phoenix","phx_reply",{"response":{},"status":"ok"}]`, messages[0])
+	assert.JSONEq(t, `["","2","phoenix","phx_reply",{"response":{},"status":"ok"}]`, messages[1])
+
+	resp = post(t, srv.URL+"?token=unknown", `[null,"3","phoenix","heartbeat",{}]`)
+	assert.Equal(t, http.StatusGone, resp.Status)
+}
+
+func TestLongPollIdleExpiry(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	closed := make(chan struct{})
+
+	tr := New("/live/longpoll", WithIdleTimeout(20*time.Millisecond))
+	handle := func(c channel.Conn) {
+		channel.NewServer(c, nil).Listen(ctx)
+		close(closed)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tr.Serve(handle, w, r)
+	}))
+	defer srv.Close()
+
+	resp := get(t, srv.URL)
+	assert.Equal(t, http.StatusGone, resp.Status)
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		t.Fatal("session was not expired")
+	}
+
+	resp = post(t, srv.URL+"?token="+resp.Token, `[null,"1","phoenix","heartbeat",{}]`)
+	assert.Equal(t, http.StatusGone, resp.Status)
+}
+
+func TestLongPollSlowClient(t *testing.T) {
+	sess := newSession("token")
+	defer sess.close()
+
+	srv := channel.NewServer(sess, nil,
+		channel.WithQueueSize(2),
+		channel.WithSlowClientPolicy(channel.Drop, 0),
+	)
+
+	// a client that doesn't poll fills the outbox, then the queue.
+	var err error
+	sent := 0
+	for sent < outboxSize+10 {
+		if err = srv.Push(&channel.Message{Event: "diff"}); err != nil {
+			break
+		}
+		sent++
+		time.Sleep(time.Millisecond)
+	}
+	assert.ErrorIs(t, err, channel.ErrQueueFull)
+	assert.GreaterOrEqual(t, sent, outboxSize)
+
+	// what was taken is still delivered once it polls.
+	received := 0
+	for {
+		messages, ok := sess.wait(context.Background(), 10*time.Millisecond)
+		assert.True(t, ok)
+		if len(messages) == 0 {
+			break
+		}
+		received += len(messages)
+	}
+	assert.Equal(t, sent, received)
+}
+
+func get(t *testing.T, url string) pollResponse {
+	t.Helper()
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return decodeResponse(t, res)
+}
+
+func post(t *testing.T, url string, body string) pollResponse {
+	t.Helper()
+
+	res, err := http.Post(url, "application/x-ndjson", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return decodeResponse(t, res)
+}
+
+func decodeResponse(t *testing.T, res *http.Response) pollResponse {
+	t.Helper()
+	defer res.Body.Close()
+
+	resp := pollResponse{}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+
+	return resp
+}
@@ -56,3 +56,7 @@ func (t *wsConn) ReadMessage() ([]byte, error) {
 func (t *wsConn) WriteMessage(data []byte) error {
 	return wsutil.WriteServerMessage(t.conn, ws.OpText, data)
 }
+
+func (t *wsConn) Close() error {
+	return t.conn.Close()
+}
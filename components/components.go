@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/sethpollack/go-live-view/html"
+	lv "github.com/sethpollack/go-live-view/liveview"
 	"github.com/sethpollack/go-live-view/rend"
 	"github.com/sethpollack/go-live-view/std"
 	"github.com/sethpollack/go-live-view/uploads"
@@ -25,8 +26,10 @@ func Unpkg(pkg, version string) rend.Node {
 func RootLayout(children ...rend.Node) rend.Node {
 	return html.Html(
 		html.Head(
+			lv.CSRFMeta(),
+			lv.LiveTitle(lv.WithTitleSuffix(" · go-live-view"), lv.WithDefaultTitle("Examples")),
 			Unpkg("phoenix", "1.7.14"),
-			Unpkg("phoenix_live_view", "1.0.0-rc.6"),
+			Unpkg("phoenix_live_view", "1.1.0"),
 			Unpkg("topbar", "2.0.2"),
 			Unpkg("apexcharts", "3.26.0"),
 		),
@@ -74,3 +77,25 @@ func UploadInput(u *uploads.Config, children ...rend.Node) rend.Node {
 			),
 		))
 }
+
+// FlashGroup renders the current flash, a message per kind. Clicking a
+// message clears it.
+func FlashGroup() rend.Node {
+	return lv.RenderFlash(func(flash lv.Flash) rend.Node {
+		return html.Div(
+			html.Attr("id", "flash-group"),
+			std.Range(flash.Kinds(), func(kind string) rend.Node {
+				return html.Div(
+					html.Attrs(
+						html.Attr("id", "flash-"+kind),
+						html.Attr("class", "flash flash-"+kind),
+						html.Attr("role", "alert"),
+						html.Attr("phx-click", "lv:clear-flash"),
+						html.Attr("phx-value-key", kind),
+					),
+					std.Text(flash.Get(kind)),
+				)
+			}),
+		)
+	})
+}
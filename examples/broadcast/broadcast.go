@@ -33,8 +33,8 @@ func (l *Live) Mount(s lv.Socket, _ params.Params) error {
 	return nil
 }
 
-func (l *Live) Event(s lv.Socket, event string, _ params.Params) error {
-	if event == "update" {
+func (l *Live) Info(s lv.Socket, msg any) error {
+	if info, ok := msg.(lv.Info); ok && info.Event == "update" {
 		go func() {
 			time.Sleep(1 * time.Second)
 			s.PushSelf("update", nil)
@@ -65,8 +65,8 @@ func (l *Live) Mount(s lv.Socket, _ params.Params) error {
 	return nil
 }
 
-func (l *Live) Event(s lv.Socket, event string, _ params.Params) error {
-	if event == "update-chart" {
+func (l *Live) Info(s lv.Socket, msg any) error {
+	if info, ok := msg.(lv.Info); ok && info.Event == "update-chart" {
 		go func() {
 			time.Sleep(5 * time.Second)
 			s.PushSelf("update-chart", nil)
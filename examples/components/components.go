@@ -0,0 +1,76 @@
+package components
+
+import (
+	"github.com/sethpollack/go-live-view/html"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/std"
+)
+
+type Live struct {
+	Renders int
+}
+
+func (l *Live) Event(s lv.Socket, event string, _ params.Params) error {
+	if event == "refresh" {
+		l.Renders++
+	}
+
+	return nil
+}
+
+func (l *Live) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(
+		html.H1(
+			std.Textf("Parent renders: %d", &l.Renders),
+		),
+		html.Button(
+			std.Text("refresh parent"),
+			html.Attr("phx-click", "refresh"),
+		),
+		lv.LiveComponent("first", &Counter{Label: "First"}),
+		lv.LiveComponent("second", &Counter{Label: "Second"}),
+	), nil
+}
+
+// Counter keeps its own count, events from its buttons never reach the
+// parent.
+type Counter struct {
+	Label string
+	Count int
+}
+
+func (c *Counter) Update(_ lv.Socket, next any) error {
+	c.Label = next.(*Counter).Label
+	return nil
+}
+
+func (c *Counter) Event(_ lv.Socket, event string, _ params.Params) error {
+	switch event {
+	case "inc":
+		c.Count++
+	case "dec":
+		c.Count--
+	}
+
+	return nil
+}
+
+func (c *Counter) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(
+		html.H2(
+			std.Textf("%s: %d", &c.Label, &c.Count),
+		),
+		html.Button(
+			std.Text("inc"),
+			html.Attr("phx-click", "inc"),
+			lv.Myself(),
+		),
+		html.Button(
+			std.Text("dec"),
+			html.Attr("phx-click", "dec"),
+			lv.Myself(),
+		),
+	), nil
+}
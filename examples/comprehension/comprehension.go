@@ -53,7 +53,7 @@ func (l *Live) Render(_ rend.Node) (rend.Node, error) {
 		),
 		html.Table(
 			html.Tbody(
-				std.Range[*User](l.users, func(u *User) rend.Node {
+				std.RangeKeyed(l.users, func(u *User) int { return u.ID }, func(u *User) rend.Node {
 					return html.Tr(
 						html.Td(
 							std.Text(&u.Name),
@@ -1,6 +1,8 @@
 package counter
 
 import (
+	"fmt"
+
 	"github.com/sethpollack/go-live-view/html"
 	lv "github.com/sethpollack/go-live-view/liveview"
 	"github.com/sethpollack/go-live-view/params"
@@ -25,6 +27,10 @@ func (l *Live) Event(s lv.Socket, event string, _ params.Params) error {
 	return nil
 }
 
+func (l *Live) Title() string {
+	return fmt.Sprintf("Counter (%d)", l.Count)
+}
+
 func (l *Live) Render(_ rend.Node) (rend.Node, error) {
 	return html.Div(
 		html.H1(
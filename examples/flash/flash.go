@@ -8,9 +8,7 @@ import (
 	"github.com/sethpollack/go-live-view/std"
 )
 
-type Live struct {
-	flashes []string
-}
+type Live struct{}
 
 func (l *Live) Event(s lv.Socket, event string, p params.Params) error {
 	switch event {
@@ -22,6 +20,12 @@ func (l *Live) Event(s lv.Socket, event string, p params.Params) error {
 		s.Redirect("/", lv.WithFlash("info", "from redirect!"))
 	case "no-flash-navigate":
 		s.PushNavigate("/")
+	case "put":
+		s.PutFlash("info", "from event!")
+	case "error":
+		s.PutFlash("error", "something went wrong")
+	case "clear":
+		s.ClearFlash()
 	}
 
 	return nil
@@ -43,9 +47,15 @@ func (l *Live) Render(_ rend.Node) (rend.Node, error) {
 		),
 		html.Button(
 			std.Text("flash"),
-			html.Attr("phx-click", "lv:flash"),
-			html.Attr("phx-value-key", "info"),
-			html.Attr("phx-value-msg", "from event"),
+			html.Attr("phx-click", "put"),
+		),
+		html.Button(
+			std.Text("error"),
+			html.Attr("phx-click", "error"),
+		),
+		html.Button(
+			std.Text("clear"),
+			html.Attr("phx-click", "clear"),
 		),
 		html.Button(
 			std.Text("no flash navigate"),
@@ -0,0 +1,78 @@
+package forms
+
+import (
+	"errors"
+	"regexp"
+
+	"github.com/sethpollack/go-live-view/form"
+	"github.com/sethpollack/go-live-view/html"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/std"
+)
+
+type signup struct {
+	Email string `param:"email"`
+	Name  string `param:"name"`
+	Age   int    `param:"age"`
+	Terms bool   `param:"terms"`
+}
+
+var validator = form.Validator{
+	"email": {form.Required(), form.Format(regexp.MustCompile(`^[^\s@]+@[^\s@]+$`))},
+	"name":  {form.Required(), form.Length(2, 40)},
+	"age":   {form.Range(13, 130)},
+	"terms": {func(v any) error {
+		if v != "true" {
+			return errors.New("must be accepted")
+		}
+		return nil
+	}},
+}
+
+type Live struct {
+	form   *form.Form
+	signup *signup
+}
+
+func (l *Live) Mount(s lv.Socket, p params.Params) error {
+	l.form = form.New("signup", signup{}, validator)
+	return nil
+}
+
+func (l *Live) Event(s lv.Socket, event string, p params.Params) error {
+	switch event {
+	case "validate":
+		l.form.Change(p.Map("value"))
+	case "save":
+		if !l.form.Submit(p.Map("value")) {
+			return nil
+		}
+
+		var su signup
+		if l.form.Bind(&su) == nil {
+			l.signup = &su
+		}
+	}
+
+	return nil
+}
+
+func (l *Live) Render(_ rend.Node) (rend.Node, error) {
+	if l.signup != nil {
+		return html.P(std.Textf("Welcome %s!", l.signup.Name)), nil
+	}
+
+	f := l.form
+
+	return html.Form(
+		html.Attr("phx-change", "validate"),
+		html.Attr("phx-submit", "save"),
+		html.Div(f.Label("email", "Email"), f.Input("email", html.InputTypeAttr("email"))),
+		html.Div(f.Label("name", "Name"), f.Input("name")),
+		html.Div(f.Label("age", "Age"), f.Input("age", html.InputTypeAttr("number"))),
+		html.Div(f.Checkbox("terms"), f.Label("terms", "I accept the terms")),
+		html.Button(std.Text("Sign up")),
+	), nil
+}
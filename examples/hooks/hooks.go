@@ -0,0 +1,71 @@
+package hooks
+
+import (
+	"fmt"
+
+	"github.com/sethpollack/go-live-view/html"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/std"
+)
+
+// Ping is sent by the Ping hook in examples/main.go:
+//
+//	this.pushEvent("ping", {sent: Date.now()}, (pong) => ...)
+type Ping struct {
+	Sent int64 `param:"sent"`
+}
+
+// Pong is the reply to a Ping.
+type Pong struct {
+	Count int   `json:"count"`
+	Sent  int64 `json:"sent"`
+}
+
+type Live struct {
+	Pings int
+}
+
+func (l *Live) EventReply(s lv.Socket, event string, p params.Params) (any, error) {
+	if event != "ping" {
+		return nil, nil
+	}
+
+	var ping Ping
+	err := params.Bind(p.Map("value"), &ping)
+	if err != nil {
+		return nil, err
+	}
+
+	l.Pings++
+
+	err = s.PushEvents(
+		lv.ClientEvent{Event: "log", Payload: map[string]any{"msg": fmt.Sprintf("ping %d", l.Pings)}},
+		lv.ClientEvent{Event: "highlight", Payload: map[string]any{"id": "pings"}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return Pong{Count: l.Pings, Sent: ping.Sent}, nil
+}
+
+func (l *Live) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(
+		html.Button(
+			html.IdAttr("ping"),
+			html.Attr("phx-hook", "Ping"),
+			std.Text("ping"),
+		),
+		html.P(
+			html.IdAttr("pings"),
+			std.Text("pings: "),
+			std.Text(&l.Pings),
+		),
+		html.P(
+			html.IdAttr("latency"),
+			html.Attr("phx-update", "ignore"),
+		),
+	), nil
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
 	"log"
 	"net/http"
 	"os"
@@ -12,13 +15,18 @@ import (
 	"github.com/sethpollack/go-live-view/examples/async"
 	"github.com/sethpollack/go-live-view/examples/broadcast"
 	"github.com/sethpollack/go-live-view/examples/charts"
+	"github.com/sethpollack/go-live-view/examples/components"
 	"github.com/sethpollack/go-live-view/examples/comprehension"
 	"github.com/sethpollack/go-live-view/examples/counter"
 	"github.com/sethpollack/go-live-view/examples/flash"
+	"github.com/sethpollack/go-live-view/examples/forms"
+	"github.com/sethpollack/go-live-view/examples/hooks"
 	"github.com/sethpollack/go-live-view/examples/index"
 	"github.com/sethpollack/go-live-view/examples/js"
 	"github.com/sethpollack/go-live-view/examples/nested"
+	"github.com/sethpollack/go-live-view/examples/presence"
 	"github.com/sethpollack/go-live-view/examples/scroll"
+	"github.com/sethpollack/go-live-view/examples/session"
 	"github.com/sethpollack/go-live-view/examples/ssnav"
 	"github.com/sethpollack/go-live-view/examples/stream"
 	"github.com/sethpollack/go-live-view/examples/uploads"
@@ -44,14 +52,51 @@ const appJS = `
 			window.chart.updateSeries(options.series)
 		}
 	}
+	Hooks.Ping = {
+		mounted() {
+			this.el.addEventListener("click", () => {
+				this.pushEvent("ping", {sent: Date.now()}, (pong) => {
+					document.getElementById("latency").innerText = "pong " + pong.count + " in " + (Date.now() - pong.sent) + "ms"
+				})
+			})
+			this.handleEvent("log", ({msg}) => console.log(msg))
+			this.handleEvent("highlight", ({id}) => {
+				const el = document.getElementById(id)
+				el.animate([{background: "yellow"}, {background: "none"}], 500)
+			})
+		}
+	}
 
-	const lv = new LiveView.LiveSocket("/live", Phoenix.Socket, {hooks: Hooks});
+	const csrfToken = document.querySelector("meta[name='csrf-token']").getAttribute("content");
+	const lv = new LiveView.LiveSocket("/live", Phoenix.Socket, {hooks: Hooks, params: {_csrf_token: csrfToken}});
 	lv.connect();
 
 	window.liveSocket = lv;
 })();
 `
 
+var (
+	secretKey = secretKeyBase()
+	sessions  = handler.NewCookieSession(secretKey)
+)
+
+// secretKeyBase reads SECRET_KEY_BASE, falling back to a random secret so
+// the examples run without one. Sessions don't survive a restart then.
+func secretKeyBase() string {
+	if secret := os.Getenv("SECRET_KEY_BASE"); secret != "" {
+		return secret
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("SECRET_KEY_BASE isn't set, using a random secret")
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
 func setupRoutes() lv.Router {
 	rt := router.NewRouter(
 		comp.RootLayout,
@@ -63,6 +108,7 @@ func setupRoutes() lv.Router {
 			"/broadcast",
 			"/chart",
 			"/comprehension",
+			"/components",
 			"/counter",
 			"/nested",
 			"/ssnav",
@@ -71,6 +117,10 @@ func setupRoutes() lv.Router {
 			"/uploads",
 			"/js",
 			"/flash",
+			"/forms",
+			"/hooks",
+			"/presence",
+			"/login",
 		},
 	})
 
@@ -80,23 +130,41 @@ func setupRoutes() lv.Router {
 	root.Handle("/async", &async.Live{})
 	root.Handle("/broadcast", broadcast.New())
 	root.Handle("/comprehension", &comprehension.Live{})
+	root.Handle("/components", &components.Live{})
 	root.Handle("/stream", &stream.Live{})
 	root.Handle("/scroll", &scroll.Live{})
 	root.Handle("/js", &js.Live{})
 	root.Handle("/flash", &flash.Live{})
+	root.Handle("/forms", &forms.Live{})
+	root.Handle("/hooks", &hooks.Live{})
+	root.Handle("/presence", &presence.Live{})
+	root.Handle("/login", &session.Live{Sessions: sessions})
 
 	nest := root.Group("/nested", &nested.Live{})
-	nest.Handle("/:id", &nested.ShowLive{})
-	nest.Handle("/:id/edit", &nested.EditLive{})
+	nest.Handle("/:id", &nested.ShowLive{}, router.WithName("nested_show"))
+	nest.Handle("/:id/edit", &nested.EditLive{}, router.WithName("nested_edit"))
 
 	snav := root.Group("/ssnav", &ssnav.Live{})
-	snav.Handle("/:id", &ssnav.ShowLive{})
-	snav.Handle("/:id/edit", &ssnav.EditLive{})
+	snav.Handle("/:id", &ssnav.ShowLive{}, router.WithName("ssnav_show"))
+	snav.Handle("/:id/edit", &ssnav.EditLive{}, router.WithName("ssnav_edit"))
 
 	return rt
 }
 
+var pathsFile = flag.String("paths", "", "write path helpers for the named routes to this file and exit")
+
+//go:generate go run . -paths paths/paths.go
+
 func main() {
+	flag.Parse()
+
+	if *pathsFile != "" {
+		if err := writePaths(*pathsFile); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	ctx := context.Background()
 
 	mux := http.NewServeMux()
@@ -109,7 +177,13 @@ func main() {
 		w.Write([]byte(""))
 	}))
 
-	mux.Handle("/", handler.NewHandler(ctx, setupRoutes))
+	mux.Handle("/session", sessions)
+
+	mux.Handle("/", handler.NewHandler(ctx, setupRoutes,
+		handler.WithSessionGetter(sessions),
+		handler.WithSecretKey(secretKey),
+		handler.WithCSRF(),
+	))
 
 	srv := &http.Server{
 		Addr: "0.0.0.0:8080",
@@ -140,3 +214,13 @@ func main() {
 	log.Println("shutting down")
 	os.Exit(0)
 }
+
+func writePaths(name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return router.GeneratePaths(f, "paths", setupRoutes())
+}
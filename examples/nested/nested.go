@@ -1,6 +1,7 @@
 package nested
 
 import (
+	"github.com/sethpollack/go-live-view/examples/paths"
 	"github.com/sethpollack/go-live-view/html"
 	lv "github.com/sethpollack/go-live-view/liveview"
 	"github.com/sethpollack/go-live-view/params"
@@ -19,7 +20,7 @@ func (u *Live) Render(child rend.Node) (rend.Node, error) {
 		html.Button(
 			html.A(
 				std.Text("Show"),
-				html.AHrefAttr("/nested/1"),
+				html.AHrefAttr(paths.NestedShowPath("1")),
 				html.DataAttr("phx-link", "patch"),
 				html.DataAttr("phx-link-state", "push"),
 			),
@@ -27,7 +28,7 @@ func (u *Live) Render(child rend.Node) (rend.Node, error) {
 		html.Button(
 			html.A(
 				std.Text("Edit"),
-				html.AHrefAttr("/nested/1/edit"),
+				html.AHrefAttr(paths.NestedEditPath("1")),
 				html.DataAttr("phx-link", "patch"),
 				html.DataAttr("phx-link-state", "push"),
 			),
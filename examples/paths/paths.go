@@ -0,0 +1,25 @@
+// Code generated by router.GeneratePaths. DO NOT EDIT.
+
+package paths
+
+import "net/url"
+
+// NestedEditPath returns the path for nested_edit, /nested/:id/edit.
+func NestedEditPath(id string) string {
+	return "/nested/" + url.PathEscape(id) + "/edit"
+}
+
+// NestedShowPath returns the path for nested_show, /nested/:id.
+func NestedShowPath(id string) string {
+	return "/nested/" + url.PathEscape(id)
+}
+
+// SsnavEditPath returns the path for ssnav_edit, /ssnav/:id/edit.
+func SsnavEditPath(id string) string {
+	return "/ssnav/" + url.PathEscape(id) + "/edit"
+}
+
+// SsnavShowPath returns the path for ssnav_show, /ssnav/:id.
+func SsnavShowPath(id string) string {
+	return "/ssnav/" + url.PathEscape(id)
+}
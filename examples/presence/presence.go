@@ -0,0 +1,78 @@
+package presence
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+
+	"github.com/sethpollack/go-live-view/html"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/presence"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/std"
+)
+
+const topic = "presence:lobby"
+
+var (
+	tracker = presence.New()
+	users   atomic.Int64
+)
+
+type Live struct {
+	name  string
+	state presence.State
+}
+
+func (l *Live) Mount(s lv.Socket, _ params.Params) error {
+	l.state = tracker.List(topic)
+
+	if s == nil {
+		return nil
+	}
+
+	l.name = fmt.Sprintf("user-%d", users.Add(1))
+
+	err := s.Subscribe(topic)
+	if err != nil {
+		return err
+	}
+
+	return tracker.Track(s, topic, l.name, map[string]any{
+		"online_at": "now",
+	})
+}
+
+func (l *Live) Info(s lv.Socket, msg any) error {
+	if info, ok := msg.(lv.Info); ok && info.Event == presence.DiffEvent {
+		diff, err := presence.DecodeDiff(info.Payload)
+		if err != nil {
+			return err
+		}
+		l.state.Apply(diff)
+	}
+
+	return nil
+}
+
+func (l *Live) Render(_ rend.Node) (rend.Node, error) {
+	names := []string{}
+	for name := range l.state {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return html.Div(
+		html.H1(
+			std.Textf("You are %s", &l.name),
+		),
+		html.Ul(
+			std.Range(names, func(name string) rend.Node {
+				return html.Li(
+					std.Text(&name),
+				)
+			}),
+		),
+	), nil
+}
@@ -0,0 +1,68 @@
+package session
+
+import (
+	"net/http"
+
+	"github.com/sethpollack/go-live-view/handler"
+	"github.com/sethpollack/go-live-view/html"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/std"
+)
+
+type Live struct {
+	Sessions *handler.CookieSession
+
+	user string
+}
+
+// HttpMount reads the user from the cookie for the first render.
+func (l *Live) HttpMount(w http.ResponseWriter, r *http.Request, p params.Params) error {
+	l.user, _ = l.Sessions.Get(r)["user"].(string)
+	return nil
+}
+
+// Mount reads the user from the session the socket joined with. Params
+// can't be trusted, they include the url's query.
+func (l *Live) Mount(s lv.Socket, p params.Params) error {
+	if s != nil {
+		l.user, _ = s.Session()["user"].(string)
+	}
+	return nil
+}
+
+func (l *Live) Event(s lv.Socket, event string, p params.Params) error {
+	switch event {
+	case "login":
+		return l.Sessions.Redirect(s, "/login", map[string]any{
+			"user": p.Map("value").String("name"),
+		})
+	case "logout":
+		return l.Sessions.Redirect(s, "/login", map[string]any{
+			"user": nil,
+		})
+	}
+
+	return nil
+}
+
+func (l *Live) Render(_ rend.Node) (rend.Node, error) {
+	if l.user == "" {
+		return html.Button(
+			std.Text("login as guest"),
+			html.Attr("phx-click", "login"),
+			html.Attr("phx-value-name", "guest"),
+		), nil
+	}
+
+	return html.Div(
+		html.H1(
+			std.Textf("signed in as %s", l.user),
+		),
+		html.Button(
+			std.Text("logout"),
+			html.Attr("phx-click", "logout"),
+		),
+	), nil
+}
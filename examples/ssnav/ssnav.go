@@ -1,6 +1,7 @@
 package ssnav
 
 import (
+	"github.com/sethpollack/go-live-view/examples/paths"
 	"github.com/sethpollack/go-live-view/html"
 	lv "github.com/sethpollack/go-live-view/liveview"
 	"github.com/sethpollack/go-live-view/params"
@@ -27,14 +28,14 @@ func (u *Live) Render(child rend.Node) (rend.Node, error) {
 			html.A(
 				std.Text("Show"),
 				html.Attr("phx-click", "navigate"),
-				html.Attr("phx-value-href", "/ssnav/1"),
+				html.Attr("phx-value-href", paths.SsnavShowPath("1")),
 			),
 		),
 		html.Button(
 			html.A(
 				std.Text("Edit"),
 				html.Attr("phx-click", "navigate"),
-				html.Attr("phx-value-href", "/ssnav/1/edit"),
+				html.Attr("phx-value-href", paths.SsnavEditPath("1")),
 			),
 		),
 		child,
@@ -0,0 +1,107 @@
+package form
+
+import (
+	"fmt"
+
+	"github.com/sethpollack/go-live-view/html"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/std"
+)
+
+// Input renders an input for field with its name, id, value and errors.
+// attrs are added to the input, like html.InputTypeAttr("email").
+func (f *Form) Input(field string, attrs ...rend.Node) rend.Node {
+	value := f.valueString(field)
+
+	return std.Group(
+		html.Input(
+			html.IdAttr(f.InputID(field)),
+			html.InputNameAttr(f.InputName(field)),
+			html.InputValueAttr(&value),
+			html.Attrs(attrs...),
+		),
+		f.ErrorTag(field),
+	)
+}
+
+// Textarea renders a textarea for field with its name, id, value and
+// errors.
+func (f *Form) Textarea(field string, attrs ...rend.Node) rend.Node {
+	value := f.valueString(field)
+
+	return std.Group(
+		html.Textarea(
+			html.IdAttr(f.InputID(field)),
+			html.TextareaNameAttr(f.InputName(field)),
+			html.Attrs(attrs...),
+			std.Text(&value),
+		),
+		f.ErrorTag(field),
+	)
+}
+
+// Checkbox renders a checkbox for field, along with a hidden input so
+// unchecking it sends "false".
+func (f *Form) Checkbox(field string, attrs ...rend.Node) rend.Node {
+	checkbox := []rend.Node{
+		html.InputTypeAttr("checkbox"),
+		html.IdAttr(f.InputID(field)),
+		html.InputNameAttr(f.InputName(field)),
+		html.InputValueAttr("true"),
+	}
+
+	switch f.valueString(field) {
+	case "true", "on", "1":
+		checkbox = append(checkbox, html.InputCheckedAttr())
+	}
+
+	return std.Group(
+		html.Input(
+			html.InputTypeAttr("hidden"),
+			html.InputNameAttr(f.InputName(field)),
+			html.InputValueAttr("false"),
+		),
+		// dynamic so checking it doesn't change the statics around it.
+		std.DynamicNode(html.Input(
+			html.Attrs(checkbox...),
+			html.Attrs(attrs...),
+		)),
+		f.ErrorTag(field),
+	)
+}
+
+// Label renders a label for field's input.
+func (f *Form) Label(field string, text string, attrs ...rend.Node) rend.Node {
+	return html.Label(
+		html.LabelForAttr(f.InputID(field)),
+		html.Attrs(attrs...),
+		std.Text(text),
+	)
+}
+
+// ErrorTag renders field's errors once it's used, each in a
+// <p class="error">.
+func (f *Form) ErrorTag(field string) rend.Node {
+	errs := f.Errors(field)
+
+	nodes := make([]rend.Node, 0, len(errs))
+	for _, msg := range errs {
+		nodes = append(nodes, html.P(
+			html.ClassAttr("error"),
+			std.Text(msg),
+		))
+	}
+
+	return std.DynamicNode(std.Group(nodes...))
+}
+
+func (f *Form) valueString(field string) string {
+	switch v := f.Value(field).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
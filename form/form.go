@@ -0,0 +1,374 @@
+package form
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sethpollack/go-live-view/params"
+)
+
+// unusedPrefix marks inputs the client hasn't seen interaction with, like
+// user[_unused_name] for user[name].
+const unusedPrefix = "_unused_"
+
+// Form is the state of a form: its values, validation errors and the
+// fields the user has used. Errors are only shown for used fields, which
+// the client reports with _unused_ params in place of phx-feedback-for.
+type Form struct {
+	// Name prefixes the input names, so "user" names inputs user[email].
+	Name string
+
+	values    params.Params
+	validator Validator
+	errors    map[string][]string
+	used      map[string]bool
+	submitted bool
+}
+
+// New returns a form named name with the values of data, a params.Params
+// or a struct with param tags, validated by v.
+func New(name string, data any, v Validator) *Form {
+	f := &Form{
+		Name:      name,
+		values:    toParams(data),
+		validator: v,
+		used:      map[string]bool{},
+	}
+
+	f.validate()
+
+	return f
+}
+
+// Change updates the form from the value of a phx-change event, marking
+// the fields the user has used. After reconnecting, the client recovers
+// forms by sending their state to the form's phx-auto-recover event, or
+// its phx-change event, so Change also restores a form.
+func (f *Form) Change(p params.Params) {
+	values := f.scope(p)
+
+	f.used = map[string]bool{}
+	f.values = clean(values, "", f.used)
+
+	target := p.Target()
+	if len(target) > 0 && (f.Name == "" || target[0] == f.Name) {
+		if f.Name != "" {
+			target = target[1:]
+		}
+		if len(target) > 0 {
+			f.used[path(target)] = true
+		}
+	}
+
+	f.validate()
+}
+
+// Submit updates the form from the value of a phx-submit event, marking
+// every field as used. It reports whether the form is valid.
+func (f *Form) Submit(p params.Params) bool {
+	f.Change(p)
+	f.submitted = true
+
+	return f.Valid()
+}
+
+// Valid reports whether the form has no errors, used or not.
+func (f *Form) Valid() bool {
+	return len(f.errors) == 0
+}
+
+// Bind decodes the form's values into dst with params.Bind, adding the
+// fields that fail to the form's errors.
+func (f *Form) Bind(dst any) error {
+	err := params.Bind(f.values, dst)
+
+	var errs params.Errors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			f.AddError(e.Field, e.Err.Error())
+		}
+	}
+
+	return err
+}
+
+// AddError adds msg to field's errors, for checks that need more than a
+// single field, like a password confirmation.
+func (f *Form) AddError(field, msg string) {
+	if f.errors == nil {
+		f.errors = map[string][]string{}
+	}
+
+	f.errors[field] = append(f.errors[field], msg)
+}
+
+// Errors returns field's errors once it's used.
+func (f *Form) Errors(field string) []string {
+	if !f.Used(field) {
+		return nil
+	}
+
+	return f.errors[field]
+}
+
+// Used reports whether the user has used field, or the form was submitted.
+func (f *Form) Used(field string) bool {
+	if f.submitted || f.used[field] {
+		return true
+	}
+
+	for used := range f.used {
+		if strings.HasPrefix(used, field+"[") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Params returns the form's values.
+func (f *Form) Params() params.Params {
+	return f.values
+}
+
+// Value returns field's value.
+func (f *Form) Value(field string) any {
+	return lookup(f.values, field)
+}
+
+// InputName returns the name of field's input, like user[address][city].
+func (f *Form) InputName(field string) string {
+	if f.Name == "" {
+		return field
+	}
+
+	name, rest, _ := strings.Cut(field, "[")
+	if rest != "" {
+		rest = "[" + rest
+	}
+
+	return f.Name + "[" + name + "]" + rest
+}
+
+// InputID returns the id of field's input, like user_address_city.
+func (f *Form) InputID(field string) string {
+	id := strings.NewReplacer("[]", "", "[", "_", "]", "").Replace(f.InputName(field))
+	return strings.TrimSuffix(id, "_")
+}
+
+func (f *Form) scope(p params.Params) params.Params {
+	if f.Name == "" {
+		return p
+	}
+
+	return p.Map(f.Name)
+}
+
+func (f *Form) validate() {
+	f.errors = nil
+
+	fields := make([]string, 0, len(f.validator))
+	for field := range f.validator {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		value := lookup(f.values, field)
+
+		for _, rule := range f.validator[field] {
+			err := rule(value)
+			if err != nil {
+				f.AddError(field, err.Error())
+			}
+		}
+	}
+}
+
+// clean returns m without its _unused_ markers, marking the rest as used.
+func clean(m map[string]any, prefix string, used map[string]bool) params.Params {
+	unused := map[string]bool{}
+	for key := range m {
+		if strings.HasPrefix(key, unusedPrefix) {
+			unused[strings.TrimPrefix(key, unusedPrefix)] = true
+		}
+	}
+
+	result := params.Params{}
+	for key, value := range m {
+		if strings.HasPrefix(key, unusedPrefix) || key == "_target" {
+			continue
+		}
+
+		field := key
+		if prefix != "" {
+			field = prefix + "[" + key + "]"
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			result[key] = map[string]any(clean(v, field, used))
+			continue
+		case []any:
+			list := make([]any, len(v))
+			for i, item := range v {
+				if nested, ok := item.(map[string]any); ok {
+					item = map[string]any(clean(nested, fmt.Sprintf("%s[%d]", field, i), used))
+				}
+				list[i] = item
+			}
+			value = list
+		}
+
+		result[key] = value
+		if !unused[key] {
+			used[field] = true
+		}
+	}
+
+	return result
+}
+
+// lookup returns the value at field, like address[city].
+func lookup(p params.Params, field string) any {
+	var current any = map[string]any(p)
+
+	for _, key := range split(field) {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current = m[key]
+	}
+
+	return current
+}
+
+func split(field string) []string {
+	name, rest, _ := strings.Cut(field, "[")
+
+	keys := []string{name}
+	for _, key := range strings.Split(strings.TrimSuffix(rest, "]"), "][") {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+func path(keys []string) string {
+	field := keys[0]
+	for _, key := range keys[1:] {
+		field += "[" + key + "]"
+	}
+	return field
+}
+
+// toParams returns data as params. Structs are encoded with the same
+// param tags params.Bind uses.
+func toParams(data any) params.Params {
+	switch d := data.(type) {
+	case nil:
+		return params.Params{}
+	case params.Params:
+		return params.Merge(d)
+	case map[string]any:
+		return params.Merge(d)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(data))
+	if v.Kind() != reflect.Struct {
+		return params.Params{}
+	}
+
+	return params.Params(encodeStruct(v))
+}
+
+func encodeStruct(v reflect.Value) map[string]any {
+	m := map[string]any{}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("param")
+		if tag == "-" {
+			continue
+		}
+
+		if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+			for k, value := range encodeStruct(v.Field(i)) {
+				m[k] = value
+			}
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = field.Name
+		}
+
+		value, ok := encode(v.Field(i))
+		if ok {
+			m[name] = value
+		}
+	}
+
+	return m
+}
+
+func encode(v reflect.Value) (any, bool) {
+	if v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, false
+		}
+		return encode(v.Elem())
+	}
+
+	switch value := v.Interface().(type) {
+	case time.Time:
+		if value.Equal(value.Truncate(24 * time.Hour)) {
+			return value.Format("2006-01-02"), true
+		}
+		return value.Format(time.RFC3339), true
+	case encoding.TextMarshaler:
+		text, err := value.MarshalText()
+		return string(text), err == nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return encodeStruct(v), true
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return string(v.Bytes()), true
+		}
+		list := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if value, ok := encode(v.Index(i)); ok {
+				list = append(list, value)
+			}
+		}
+		return list, true
+	case reflect.Map:
+		m := map[string]any{}
+		iter := v.MapRange()
+		for iter.Next() {
+			if value, ok := encode(iter.Value()); ok {
+				m[fmt.Sprint(iter.Key().Interface())] = value
+			}
+		}
+		return m, true
+	default:
+		return fmt.Sprint(v.Interface()), true
+	}
+}
@@ -0,0 +1,193 @@
+package form
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/sethpollack/go-live-view/html"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/rend"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	tt := []struct {
+		name  string
+		rule  Rule
+		value any
+		err   string
+	}{
+		{name: "required", rule: Required(), value: "a"},
+		{name: "required missing", rule: Required(), value: nil, err: "can't be blank"},
+		{name: "required blank", rule: Required(), value: "  ", err: "can't be blank"},
+		{name: "required empty list", rule: Required(), value: []any{}, err: "can't be blank"},
+		{name: "length", rule: Length(2, 4), value: "héé"},
+		{name: "length short", rule: Length(2, 4), value: "a", err: "should be at least 2 character(s)"},
+		{name: "length long", rule: Length(2, 4), value: "abcde", err: "should be at most 4 character(s)"},
+		{name: "length no max", rule: Length(2, 0), value: "abcdefgh"},
+		{name: "length list", rule: Length(2, 0), value: []any{"a"}, err: "should be at least 2 item(s)"},
+		{name: "length blank", rule: Length(2, 4), value: ""},
+		{name: "format", rule: Format(regexp.MustCompile(`^\S+@\S+$`)), value: "a@b"},
+		{name: "format invalid", rule: Format(regexp.MustCompile(`^\S+@\S+$`)), value: "ab", err: "has invalid format"},
+		{name: "range", rule: Range(1, 10), value: "5"},
+		{name: "range low", rule: Range(1, 10), value: "0", err: "must be greater than or equal to 1"},
+		{name: "range high", rule: Range(1, 10), value: float64(11), err: "must be less than or equal to 10"},
+		{name: "range not a number", rule: Range(1, 10), value: "x", err: "is not a number"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.rule(tc.value)
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+type address struct {
+	City string `param:"city"`
+}
+
+type user struct {
+	Name    string    `param:"name"`
+	Age     int       `param:"age"`
+	Admin   bool      `param:"admin"`
+	Born    time.Time `param:"born"`
+	Address address   `param:"address"`
+	Nick    *string   `param:"nick"`
+}
+
+var userValidator = Validator{
+	"name":          {Required(), Length(2, 10)},
+	"age":           {Range(18, 130)},
+	"address[city]": {Required()},
+	"nick": {func(v any) error {
+		if v == "root" {
+			return errors.New("is reserved")
+		}
+		return nil
+	}},
+}
+
+func TestNewFromStruct(t *testing.T) {
+	f := New("user", user{
+		Name:    "bob",
+		Age:     30,
+		Born:    time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC),
+		Address: address{City: "Paris"},
+	}, userValidator)
+
+	assert.Equal(t, params.Params{
+		"name":    "bob",
+		"age":     "30",
+		"admin":   "false",
+		"born":    "2000-01-02",
+		"address": map[string]any{"city": "Paris"},
+	}, f.Params())
+	assert.True(t, f.Valid())
+
+	var u user
+	assert.NoError(t, f.Bind(&u))
+	assert.Equal(t, "Paris", u.Address.City)
+	assert.Equal(t, time.Date(2000, 1, 2, 0, 0, 0, 0, time.UTC), u.Born)
+}
+
+func TestChange(t *testing.T) {
+	f := New("user", nil, userValidator)
+
+	assert.False(t, f.Valid())
+	assert.Nil(t, f.Errors("name"))
+
+	p, err := params.DecodeForm(
+		"user[name]=b&user[age]=&user[_unused_age]=&user[address][city]=&user[address][_unused_city]=&user[nick]=root&user[_unused_nick]=&_target=user%5Bname%5D",
+	)
+	assert.NoError(t, err)
+
+	f.Change(p)
+
+	assert.Equal(t, []string{"should be at least 2 character(s)"}, f.Errors("name"))
+	assert.Nil(t, f.Errors("age"))
+	assert.Nil(t, f.Errors("address[city]"))
+	assert.Nil(t, f.Errors("nick"))
+	assert.False(t, f.Used("address"))
+	assert.Equal(t, params.Params{
+		"name":    "b",
+		"age":     "",
+		"nick":    "root",
+		"address": map[string]any{"city": ""},
+	}, f.Params())
+
+	assert.False(t, f.Submit(p))
+	assert.Equal(t, []string{"can't be blank"}, f.Errors("address[city]"))
+	assert.Equal(t, []string{"is reserved"}, f.Errors("nick"))
+	assert.True(t, f.Used("address"))
+}
+
+func TestBindErrors(t *testing.T) {
+	f := New("", params.Params{"age": "old"}, nil)
+	f.Submit(params.Params{"age": "old"})
+
+	var u user
+	assert.Error(t, f.Bind(&u))
+	assert.False(t, f.Valid())
+	assert.Equal(t, []string{`invalid number "old"`}, f.Errors("age"))
+}
+
+func TestInputNames(t *testing.T) {
+	f := New("user", nil, nil)
+	assert.Equal(t, "user[name]", f.InputName("name"))
+	assert.Equal(t, "user_name", f.InputID("name"))
+	assert.Equal(t, "user[address][city]", f.InputName("address[city]"))
+	assert.Equal(t, "user_address_city", f.InputID("address[city]"))
+	assert.Equal(t, "user_tags", f.InputID("tags[]"))
+
+	f = New("", nil, nil)
+	assert.Equal(t, "address[city]", f.InputName("address[city]"))
+	assert.Equal(t, "address_city", f.InputID("address[city]"))
+}
+
+func TestFields(t *testing.T) {
+	f := New("user", params.Params{"name": "<b>", "admin": "true", "bio": "hi"}, Validator{
+		"name": {Length(5, 0)},
+	})
+	f.Submit(params.Params{"user": map[string]any{"name": "<b>", "admin": "true", "bio": "hi"}})
+
+	tt := []struct {
+		name     string
+		node     rend.Node
+		expected string
+	}{
+		{
+			name:     "input",
+			node:     f.Input("name", html.InputTypeAttr("text")),
+			expected: `<input id="user_name" name="user[name]" value="&lt;b&gt;" type="text"/><p class="error">should be at least 5 character(s)</p>`,
+		},
+		{
+			name:     "textarea",
+			node:     f.Textarea("bio"),
+			expected: `<textarea id="user_bio" name="user[bio]">hi</textarea>`,
+		},
+		{
+			name:     "checkbox",
+			node:     f.Checkbox("admin"),
+			expected: `<input type="hidden" name="user[admin]" value="false"/><input type="checkbox" id="user_admin" name="user[admin]" value="true" checked/>`,
+		},
+		{
+			name:     "label",
+			node:     f.Label("name", "Name"),
+			expected: `<label for="user_name">Name</label>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, rend.RenderString(tc.node))
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package form
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Rule validates the value of a field, which is a string for most inputs.
+// Any func(any) error is a rule, so custom rules are written as funcs.
+type Rule func(value any) error
+
+// Validator is the rules for each field, by name in form notation, like
+// address[city].
+type Validator map[string][]Rule
+
+// Required fails on missing, blank and empty values.
+func Required() Rule {
+	return func(value any) error {
+		if blank(value) {
+			return errors.New("can't be blank")
+		}
+		return nil
+	}
+}
+
+// Length checks the number of characters, or items for lists. A max of 0
+// means no maximum. Blank values are left to Required.
+func Length(min, max int) Rule {
+	return func(value any) error {
+		if blank(value) {
+			return nil
+		}
+
+		n := utf8.RuneCountInString(fmt.Sprint(value))
+		unit := "character(s)"
+
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Map {
+			n = rv.Len()
+			unit = "item(s)"
+		}
+
+		if n < min {
+			return fmt.Errorf("should be at least %d %s", min, unit)
+		}
+		if max > 0 && n > max {
+			return fmt.Errorf("should be at most %d %s", max, unit)
+		}
+		return nil
+	}
+}
+
+// Format checks the value matches re. Blank values are left to Required.
+func Format(re *regexp.Regexp) Rule {
+	return func(value any) error {
+		if blank(value) {
+			return nil
+		}
+
+		if !re.MatchString(fmt.Sprint(value)) {
+			return errors.New("has invalid format")
+		}
+		return nil
+	}
+}
+
+// Range checks the value is a number between min and max. Blank values are
+// left to Required.
+func Range(min, max float64) Rule {
+	return func(value any) error {
+		if blank(value) {
+			return nil
+		}
+
+		var n float64
+		switch v := value.(type) {
+		case float64:
+			n = v
+		case int:
+			n = float64(v)
+		default:
+			f, err := strconv.ParseFloat(strings.TrimSpace(fmt.Sprint(v)), 64)
+			if err != nil {
+				return errors.New("is not a number")
+			}
+			n = f
+		}
+
+		if n < min {
+			return fmt.Errorf("must be greater than or equal to %v", min)
+		}
+		if n > max {
+			return fmt.Errorf("must be less than or equal to %v", max)
+		}
+		return nil
+	}
+}
+
+func blank(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []any:
+		return len(v) == 0
+	case []string:
+		return len(v) == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sethpollack/go-live-view/channel"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+)
+
+const (
+	csrfCookie    = "_csrf_token"
+	csrfParam     = "_csrf_token"
+	csrfTokenSize = 18
+)
+
+type csrfOption func(*csrf)
+
+// csrf keeps the unmasked token in a cookie and hands each page a freshly
+// masked copy, so the token never appears the same way twice in a response.
+type csrf struct {
+	origins []string
+}
+
+// WithCSRF requires the csrf token from <meta name="csrf-token"> (see
+// lv.CSRFMeta) on socket connect and join. The client passes it with
+// `new LiveSocket("/live", Socket, {params: {_csrf_token: token}})`.
+func WithCSRF(opts ...csrfOption) handlerOption {
+	return func(h *handler) {
+		c := &csrf{}
+		for _, opt := range opts {
+			opt(c)
+		}
+		h.csrf = c
+	}
+}
+
+// WithAllowedOrigins allows sockets from origins other than the page's
+// own host, e.g. "https://example.com".
+func WithAllowedOrigins(origins ...string) csrfOption {
+	return func(c *csrf) {
+		c.origins = append(c.origins, origins...)
+	}
+}
+
+// issue returns a masked token for the page, setting the cookie when the
+// request doesn't already carry one.
+func (c *csrf) issue(w http.ResponseWriter, r *http.Request) (string, error) {
+	token := c.cookieToken(r)
+	if token == nil {
+		token = make([]byte, csrfTokenSize)
+		if _, err := rand.Read(token); err != nil {
+			return "", err
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     csrfCookie,
+			Value:    base64.RawURLEncoding.EncodeToString(token),
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	return mask(token)
+}
+
+func (c *csrf) cookieToken(r *http.Request) []byte {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil {
+		return nil
+	}
+
+	token, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(token) != csrfTokenSize {
+		return nil
+	}
+
+	return token
+}
+
+// valid reports whether masked unmasks to the request's cookie token.
+func (c *csrf) valid(r *http.Request, masked string) bool {
+	token := c.cookieToken(r)
+	if token == nil {
+		return false
+	}
+
+	unmasked, ok := unmask(masked)
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(token, unmasked) == 1
+}
+
+// checkOrigin allows requests from the same host or an allowed origin.
+// Browsers always send Origin on websocket handshakes and cross-origin
+// requests, so a request without one is either same-origin or not from a
+// browser. Neither can ride a victim's cookies, and the token is still
+// checked on join.
+func (c *csrf) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, o := range c.origins {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// protect refuses joins unless the token the socket connected with matches
+// the cookie, and for liveviews the token sent on join too. Connections
+// are accepted either way, the client only reloads the page, picking up a
+// fresh token, when a join is refused with "unauthorized".
+func (c *csrf) protect(r *http.Request, f func() channel.Channel, live bool) func() channel.Channel {
+	connected := c.valid(r, r.URL.Query().Get(csrfParam))
+
+	return func() channel.Channel {
+		return &csrfChannel{
+			Channel:   f(),
+			csrf:      c,
+			r:         r,
+			connected: connected,
+			live:      live,
+		}
+	}
+}
+
+type csrfChannel struct {
+	channel.Channel
+
+	csrf      *csrf
+	r         *http.Request
+	connected bool
+	live      bool
+}
+
+func (c *csrfChannel) Join(s channel.Socket, p any) error {
+	if !c.connected {
+		return lv.ErrInvalidToken
+	}
+
+	if c.live && !c.csrf.valid(c.r, params.FromAny(p).Map("params").String(csrfParam)) {
+		return lv.ErrInvalidToken
+	}
+
+	return c.Channel.Join(s, p)
+}
+
+func mask(token []byte) (string, error) {
+	m := make([]byte, len(token))
+	if _, err := rand.Read(m); err != nil {
+		return "", err
+	}
+
+	masked := make([]byte, 0, len(token)*2)
+	masked = append(masked, m...)
+	for i := range token {
+		masked = append(masked, token[i]^m[i])
+	}
+
+	return base64.RawURLEncoding.EncodeToString(masked), nil
+}
+
+func unmask(s string) ([]byte, bool) {
+	masked, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(masked) != csrfTokenSize*2 {
+		return nil, false
+	}
+
+	m, xored := masked[:csrfTokenSize], masked[csrfTokenSize:]
+
+	token := make([]byte, csrfTokenSize)
+	for i := range token {
+		token[i] = xored[i] ^ m[i]
+	}
+
+	return token, true
+}
@@ -0,0 +1,242 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/sethpollack/go-live-view/channel"
+	"github.com/sethpollack/go-live-view/html"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/router"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type staticLive struct{}
+
+func (l *staticLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(), nil
+}
+
+func csrfHandler(opts ...handlerOption) *handler {
+	return NewHandler(context.Background(), func() lv.Router {
+		rt := router.NewRouter(func(n ...rend.Node) rend.Node {
+			return html.Html(html.Head(lv.CSRFMeta()), html.Body(n...))
+		})
+		rt.Handle("/", &staticLive{})
+		return rt
+	}, opts...)
+}
+
+var metaRe = regexp.MustCompile(`<meta name="csrf-token" content="([^"]+)">`)
+
+func TestCSRFRender(t *testing.T) {
+	h := csrfHandler(WithCSRF())
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	cookie := cookieFrom(w, csrfCookie)
+	assert.NotNil(t, cookie)
+	assert.True(t, cookie.HttpOnly)
+
+	m := metaRe.FindStringSubmatch(w.Body.String())
+	assert.Len(t, m, 2)
+
+	// the cookie is kept and each page gets a differently masked token
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Nil(t, cookieFrom(w, csrfCookie))
+
+	m2 := metaRe.FindStringSubmatch(w.Body.String())
+	assert.Len(t, m2, 2)
+	assert.NotEqual(t, m[1], m2[1])
+
+	assert.True(t, h.csrf.valid(r, m[1]))
+	assert.True(t, h.csrf.valid(r, m2[1]))
+	assert.False(t, h.csrf.valid(r, ""))
+	assert.False(t, h.csrf.valid(r, m[1][1:]))
+	assert.False(t, h.csrf.valid(httptest.NewRequest("GET", "/", nil), m[1]))
+
+	// without WithCSRF there is nothing to render
+	w = httptest.NewRecorder()
+	csrfHandler().ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Nil(t, cookieFrom(w, csrfCookie))
+	assert.NotRegexp(t, metaRe, w.Body.String())
+}
+
+func TestCSRFOrigin(t *testing.T) {
+	c := &csrf{}
+	WithAllowedOrigins("https://app.example.com")(c)
+
+	tt := []struct {
+		name   string
+		origin string
+		ok     bool
+	}{
+		{name: "missing", origin: "", ok: true},
+		{name: "same host", origin: "http://example.com", ok: true},
+		{name: "allowed", origin: "https://app.example.com", ok: true},
+		{name: "other", origin: "https://evil.com", ok: false},
+		{name: "other port", origin: "http://example.com:8080", ok: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "http://example.com/live/websocket", nil)
+			if tc.origin != "" {
+				r.Header.Set("Origin", tc.origin)
+			}
+			assert.Equal(t, tc.ok, c.checkOrigin(r))
+		})
+	}
+
+	h := csrfHandler(WithCSRF())
+	r := httptest.NewRequest("GET", "http://example.com/live/websocket", nil)
+	r.Header.Set("Origin", "https://evil.com")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+type joinChannel struct {
+	channel.Channel
+	joined bool
+}
+
+func (c *joinChannel) Join(channel.Socket, any) error {
+	c.joined = true
+	return nil
+}
+
+func TestCSRFJoin(t *testing.T) {
+	c := &csrf{}
+
+	w := httptest.NewRecorder()
+	token, err := c.issue(w, httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+	cookie := cookieFrom(w, csrfCookie)
+
+	other, err := mask(make([]byte, csrfTokenSize))
+	assert.NoError(t, err)
+
+	tt := []struct {
+		name    string
+		connect string
+		join    string
+		cookie  bool
+		live    bool
+		ok      bool
+	}{
+		{name: "valid", connect: token, join: token, cookie: true, live: true, ok: true},
+		{name: "missing on connect", connect: "", join: token, cookie: true, live: true},
+		{name: "missing on join", connect: token, join: "", cookie: true, live: true},
+		{name: "mismatch", connect: token, join: other, cookie: true, live: true},
+		{name: "missing cookie", connect: token, join: token, live: true},
+		{name: "other channel", connect: token, cookie: true, ok: true},
+		{name: "other channel missing on connect", connect: "", cookie: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/live/websocket?_csrf_token="+tc.connect, nil)
+			if tc.cookie {
+				r.AddCookie(cookie)
+			}
+
+			inner := &joinChannel{}
+			ch := c.protect(r, func() channel.Channel { return inner }, tc.live)()
+
+			err := ch.Join(nil, map[string]any{
+				"params": map[string]any{"_csrf_token": tc.join},
+			})
+
+			if tc.ok {
+				assert.NoError(t, err)
+			} else {
+				// the client reloads the page on "unauthorized"
+				assert.EqualError(t, err, "unauthorized")
+			}
+			assert.Equal(t, tc.ok, inner.joined)
+		})
+	}
+}
+
+func TestCSRFConnect(t *testing.T) {
+	h := csrfHandler(WithCSRF())
+
+	w := httptest.NewRecorder()
+	token, err := h.csrf.issue(w, httptest.NewRequest("GET", "/", nil))
+	assert.NoError(t, err)
+	cookie := cookieFrom(w, csrfCookie)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	session := sessionRe.FindStringSubmatch(w.Body.String())[1]
+
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	tt := []struct {
+		name   string
+		token  string
+		topic  string
+		reason string
+	}{
+		{name: "liveview", token: token[1:], topic: "lv:phx-1", reason: "unauthorized"},
+		{name: "upload", token: token[1:], topic: "lvu:0", reason: "unauthorized"},
+		{name: "valid", token: token, topic: "lv:phx-1"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// the connection is accepted, the client only reloads the page
+			// when a join is refused.
+			u := "ws" + strings.TrimPrefix(srv.URL, "http") + "/live/websocket?vsn=2.0.0&_csrf_token=" + tc.token
+			conn, res, err := websocket.DefaultDialer.Dial(u, http.Header{
+				"Cookie": {cookie.String()},
+			})
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+			assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+
+			err = conn.WriteJSON([]any{"1", "1", tc.topic, "phx_join", map[string]any{
+				"url":     srv.URL + "/",
+				"session": session,
+				"params":  map[string]any{"_csrf_token": tc.token},
+			}})
+			assert.NoError(t, err)
+
+			var reply []any
+			assert.NoError(t, conn.ReadJSON(&reply))
+			if !assert.Len(t, reply, 5) {
+				return
+			}
+			assert.Equal(t, "phx_reply", reply[3])
+
+			payload := reply[4].(map[string]any)
+			if tc.reason == "" {
+				assert.Equal(t, "ok", payload["status"])
+				return
+			}
+			assert.Equal(t, map[string]any{
+				"status":   "error",
+				"response": map[string]any{"reason": tc.reason},
+			}, payload)
+		})
+	}
+}
@@ -0,0 +1,383 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+)
+
+// Erlang's external term format, enough of it for the JSON-like data put
+// in tokens. See https://www.erlang.org/doc/apps/erts/erl_ext_dist.html.
+const (
+	termVersion = 131
+
+	newFloatExt      = 70
+	smallIntegerExt  = 97
+	integerExt       = 98
+	atomExt          = 100
+	smallTupleExt    = 104
+	nilExt           = 106
+	stringExt        = 107
+	listExt          = 108
+	binaryExt        = 109
+	smallBigExt      = 110
+	mapExt           = 116
+	atomUTF8Ext      = 118
+	smallAtomUTF8Ext = 119
+)
+
+var errTerm = errors.New("invalid term")
+
+// tuple is encoded as a tuple rather than a list.
+type tuple []any
+
+// marshalTerm encodes v like term_to_binary. Values inside it should come
+// from jsonTerm, so objects become maps with binary keys, strings become
+// binaries and null becomes nil.
+func marshalTerm(v tuple) ([]byte, error) {
+	b := &bytes.Buffer{}
+	b.WriteByte(termVersion)
+
+	if err := writeTerm(b, v); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// jsonTerm is v as the generic value encoding/json would decode it to.
+func jsonTerm(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+
+	var t any
+	if err := dec.Decode(&t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func writeTerm(b *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case nil:
+		writeAtom(b, "nil")
+	case bool:
+		writeAtom(b, strconv.FormatBool(v))
+	case string:
+		b.WriteByte(binaryExt)
+		binary.Write(b, binary.BigEndian, uint32(len(v)))
+		b.WriteString(v)
+	case int64:
+		writeInt(b, v)
+	case float64:
+		b.WriteByte(newFloatExt)
+		binary.Write(b, binary.BigEndian, math.Float64bits(v))
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			writeInt(b, i)
+			return nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return writeTerm(b, f)
+	case []any:
+		if len(v) == 0 {
+			b.WriteByte(nilExt)
+			return nil
+		}
+		b.WriteByte(listExt)
+		binary.Write(b, binary.BigEndian, uint32(len(v)))
+		for _, e := range v {
+			if err := writeTerm(b, e); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(nilExt)
+	case map[string]any:
+		b.WriteByte(mapExt)
+		binary.Write(b, binary.BigEndian, uint32(len(v)))
+		// sorted, like Erlang's small maps, so tokens are repeatable.
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			writeTerm(b, k)
+			if err := writeTerm(b, v[k]); err != nil {
+				return err
+			}
+		}
+	case tuple:
+		if len(v) > math.MaxUint8 {
+			return fmt.Errorf("tuple too large: %d", len(v))
+		}
+		b.WriteByte(smallTupleExt)
+		b.WriteByte(byte(len(v)))
+		for _, e := range v {
+			if err := writeTerm(b, e); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("can't encode %T as a term", v)
+	}
+
+	return nil
+}
+
+func writeAtom(b *bytes.Buffer, name string) {
+	b.WriteByte(smallAtomUTF8Ext)
+	b.WriteByte(byte(len(name)))
+	b.WriteString(name)
+}
+
+func writeInt(b *bytes.Buffer, i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxUint8:
+		b.WriteByte(smallIntegerExt)
+		b.WriteByte(byte(i))
+	case i >= math.MinInt32 && i <= math.MaxInt32:
+		b.WriteByte(integerExt)
+		binary.Write(b, binary.BigEndian, int32(i))
+	default:
+		sign, n := byte(0), uint64(i)
+		if i < 0 {
+			sign, n = 1, uint64(-i)
+		}
+		digits := binary.LittleEndian.AppendUint64(nil, n)
+		for len(digits) > 1 && digits[len(digits)-1] == 0 {
+			digits = digits[:len(digits)-1]
+		}
+		b.WriteByte(smallBigExt)
+		b.WriteByte(byte(len(digits)))
+		b.WriteByte(sign)
+		b.Write(digits)
+	}
+}
+
+// unmarshalTerm decodes an Erlang term written by marshalTerm or by
+// term_to_binary. Maps become map[string]any, binaries and atoms other than
+// true, false and nil become strings, and integers become int64.
+func unmarshalTerm(data []byte) (any, error) {
+	if len(data) == 0 || data[0] != termVersion {
+		return nil, errTerm
+	}
+
+	r := &termReader{data: data[1:]}
+
+	v, err := r.term()
+	if err != nil {
+		return nil, err
+	}
+	if len(r.data) != 0 {
+		return nil, errTerm
+	}
+
+	return v, nil
+}
+
+type termReader struct {
+	data []byte
+}
+
+func (r *termReader) next(n int) ([]byte, error) {
+	if n < 0 || n > len(r.data) {
+		return nil, errTerm
+	}
+
+	b := r.data[:n]
+	r.data = r.data[n:]
+
+	return b, nil
+}
+
+func (r *termReader) uint8() (int, error) {
+	b, err := r.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return int(b[0]), nil
+}
+
+func (r *termReader) uint16() (int, error) {
+	b, err := r.next(2)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint16(b)), nil
+}
+
+func (r *termReader) uint32() (int, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(b)), nil
+}
+
+func (r *termReader) term() (any, error) {
+	tag, err := r.uint8()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tag {
+	case smallIntegerExt:
+		i, err := r.uint8()
+		return int64(i), err
+	case integerExt:
+		b, err := r.next(4)
+		if err != nil {
+			return nil, err
+		}
+		return int64(int32(binary.BigEndian.Uint32(b))), nil
+	case smallBigExt:
+		n, err := r.uint8()
+		if err != nil {
+			return nil, err
+		}
+		sign, err := r.uint8()
+		if err != nil {
+			return nil, err
+		}
+		digits, err := r.next(n)
+		if err != nil || n > 8 {
+			return nil, errTerm
+		}
+		u := binary.LittleEndian.Uint64(append(slices.Clone(digits), make([]byte, 8-n)...))
+		if u > math.MaxInt64 {
+			return nil, errTerm
+		}
+		if sign != 0 {
+			return -int64(u), nil
+		}
+		return int64(u), nil
+	case newFloatExt:
+		b, err := r.next(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case atomExt, atomUTF8Ext:
+		n, err := r.uint16()
+		if err != nil {
+			return nil, err
+		}
+		return r.atom(n)
+	case smallAtomUTF8Ext:
+		n, err := r.uint8()
+		if err != nil {
+			return nil, err
+		}
+		return r.atom(n)
+	case binaryExt:
+		n, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.next(n)
+		return string(b), err
+	case stringExt:
+		n, err := r.uint16()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.next(n)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]any, len(b))
+		for i, c := range b {
+			list[i] = int64(c)
+		}
+		return list, nil
+	case nilExt:
+		return []any{}, nil
+	case listExt:
+		n, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		list, err := r.terms(n)
+		if err != nil {
+			return nil, err
+		}
+		if tail, err := r.uint8(); err != nil || tail != nilExt {
+			return nil, errTerm
+		}
+		return list, nil
+	case smallTupleExt:
+		n, err := r.uint8()
+		if err != nil {
+			return nil, err
+		}
+		list, err := r.terms(n)
+		return tuple(list), err
+	case mapExt:
+		n, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		m := make(map[string]any, min(n, len(r.data)))
+		for i := 0; i < n; i++ {
+			k, err := r.term()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, errTerm
+			}
+			if m[key], err = r.term(); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	return nil, errTerm
+}
+
+func (r *termReader) terms(n int) ([]any, error) {
+	list := make([]any, 0, min(n, len(r.data)))
+	for i := 0; i < n; i++ {
+		v, err := r.term()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+func (r *termReader) atom(n int) (any, error) {
+	b, err := r.next(n)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name := string(b); name {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "nil":
+		return nil, nil
+	default:
+		return name, nil
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerms(t *testing.T) {
+	tt := []struct {
+		name string
+		in   any
+		out  any
+	}{
+		{name: "nil", in: nil},
+		{name: "bools", in: []any{true, false}},
+		{name: "small integer", in: 7, out: int64(7)},
+		{name: "integer", in: -70000, out: int64(-70000)},
+		{name: "big integer", in: int64(-1 << 40), out: int64(-1 << 40)},
+		{name: "float", in: 1.5},
+		{name: "binary", in: "héllo"},
+		{name: "empty list", in: []any{}},
+		{name: "map", in: map[string]any{"a": []any{"b", map[string]any{}}}},
+		{name: "struct", in: struct {
+			Name string `json:"name"`
+		}{"x"}, out: map[string]any{"name": "x"}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := jsonTerm(tc.in)
+			assert.NoError(t, err)
+
+			b, err := marshalTerm(tuple{v})
+			assert.NoError(t, err)
+
+			decoded, err := unmarshalTerm(b)
+			assert.NoError(t, err)
+
+			out := tc.out
+			if out == nil {
+				out = tc.in
+			}
+			assert.Equal(t, tuple{out}, decoded)
+		})
+	}
+}
+
+func TestBadTerms(t *testing.T) {
+	tt := []struct {
+		name string
+		in   []byte
+	}{
+		{name: "empty", in: nil},
+		{name: "no version", in: []byte{smallIntegerExt, 1}},
+		{name: "truncated binary", in: []byte{termVersion, binaryExt, 0, 0, 0, 9, 'a'}},
+		{name: "improper list", in: []byte{termVersion, listExt, 0, 0, 0, 1, smallIntegerExt, 1, smallIntegerExt, 2}},
+		{name: "integer map key", in: []byte{termVersion, mapExt, 0, 0, 0, 1, smallIntegerExt, 1, nilExt}},
+		{name: "huge list", in: []byte{termVersion, listExt, 0xff, 0xff, 0xff, 0xff}},
+		{name: "trailing bytes", in: []byte{termVersion, nilExt, nilExt}},
+		{name: "unsupported", in: []byte{termVersion, 99}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := unmarshalTerm(tc.in)
+			assert.ErrorIs(t, err, errTerm)
+		})
+	}
+}
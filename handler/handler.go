@@ -2,6 +2,7 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/sethpollack/go-live-view/channel/transport/websocket"
 	"github.com/sethpollack/go-live-view/internal/lvchan"
 	"github.com/sethpollack/go-live-view/internal/lvuchan"
+	"github.com/sethpollack/go-live-view/internal/mailbox"
 	lv "github.com/sethpollack/go-live-view/liveview"
 )
 
@@ -21,8 +23,11 @@ type handler struct {
 	channels      map[string]func() channel.Channel
 	channelHub    *channel.Hub
 	transports    []channel.Transport
+	serverOptions []channel.ServerOption
 	tokenizer     tokenizer
 	sessionGetter sessionGetter
+	secretKey     string
+	csrf          *csrf
 }
 
 func NewHandler(ctx context.Context, setupRoutes func() lv.Router, opts ...handlerOption) *handler {
@@ -35,16 +40,26 @@ func NewHandler(ctx context.Context, setupRoutes func() lv.Router, opts ...handl
 			websocket.New("/live/websocket"),
 			longpoll.New("/live/longpoll"),
 		},
-		tokenizer:     &defaultTokenizer{},
 		sessionGetter: &defaultSessionGetter{},
 	}
 
-	go h.channelHub.Listen(h.ctx)
-
 	for _, opt := range opts {
 		opt(h)
 	}
 
+	if h.tokenizer == nil {
+		h.tokenizer = &defaultTokenizer{}
+		if c, ok := h.sessionGetter.(*CookieSession); ok {
+			// the page carries a copy of the session, so it's sealed
+			// like the cookie.
+			h.tokenizer = c.pageTokenizer()
+		} else if h.secretKey != "" {
+			h.tokenizer = NewSignedTokenizer(h.secretKey)
+		}
+	}
+
+	go h.channelHub.Listen(h.ctx)
+
 	return h
 }
 
@@ -54,6 +69,18 @@ func WithChannel(pattern string, f func() channel.Channel) handlerOption {
 	}
 }
 
+func WithPubSub(ps channel.PubSub) handlerOption {
+	return func(h *handler) {
+		h.channelHub = channel.NewHub(channel.WithPubSub(ps))
+	}
+}
+
+func WithServerOptions(opts ...channel.ServerOption) handlerOption {
+	return func(h *handler) {
+		h.serverOptions = append(h.serverOptions, opts...)
+	}
+}
+
 func WithTransport(transport channel.Transport) handlerOption {
 	return func(h *handler) {
 		h.transports = append(h.transports, transport)
@@ -66,6 +93,20 @@ func WithTokenizer(tokenizer tokenizer) handlerOption {
 	}
 }
 
+// WithSecretKey signs the session and static tokens embedded in the page,
+// and the flash carried through redirects, unless a tokenizer is set with
+// WithTokenizer. With a CookieSession the tokens are sealed with its secret
+// instead. It panics when secret is empty.
+func WithSecretKey(secret string) handlerOption {
+	if secret == "" {
+		panic("handler: WithSecretKey needs a secret")
+	}
+
+	return func(h *handler) {
+		h.secretKey = secret
+	}
+}
+
 func WithSessionGetter(getter sessionGetter) handlerOption {
 	return func(h *handler) {
 		h.sessionGetter = getter
@@ -75,15 +116,67 @@ func WithSessionGetter(getter sessionGetter) handlerOption {
 func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	for _, transport := range h.transports {
 		if transport.Path() == r.URL.Path {
-			transport.Serve(h.handle, w, r)
+			if h.csrf != nil && !h.csrf.checkOrigin(r) {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+
+			transport.Serve(h.connect(r), w, r)
+			return
+		}
+	}
+
+	rt := h.setupRoutes()
+
+	live := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.render(rt, w, r)
+	})
+
+	if d, ok := rt.(dispatcher); ok {
+		d.Dispatch(w, r, live)
+		return
+	}
+
+	live.ServeHTTP(w, r)
+}
+
+// dispatcher is a router that also serves plain http handlers. Requests for
+// views are passed back to live.
+type dispatcher interface {
+	Dispatch(w http.ResponseWriter, r *http.Request, live http.Handler)
+}
+
+// render writes the static render of the view for r.
+func (h *handler) render(rt lv.Router, w http.ResponseWriter, r *http.Request) {
+	if h.csrf != nil {
+		token, err := h.csrf.issue(w, r)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		r = r.WithContext(lv.WithCSRFToken(r.Context(), token))
+	}
+
+	if b, ok := h.sessionGetter.(sessionBinder); ok {
+		var err error
+		r, err = b.bind(w, r)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	}
 
 	resp, err := lv.NewLifecycle(
-		h.setupRoutes(), h.tokenizer, h.sessionGetter,
+		rt, h.tokenizer, h.sessionGetter,
 	).StaticRender(w, r)
 	if err != nil {
+		var halt *lv.HaltError
+		if errors.As(err, &halt) {
+			http.Redirect(w, r, halt.To, http.StatusFound)
+			return
+		}
+
 		switch err.(type) {
 		case lv.HttpError:
 			httpErr := err.(lv.HttpError)
@@ -100,20 +193,42 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(resp))
 }
 
-func (h *handler) handle(t channel.Conn) {
-	server := channel.NewServer(t, h.channelHub)
+// connect returns the handler for a connection opened by r.
+func (h *handler) connect(r *http.Request) func(channel.Conn) {
+	return func(t channel.Conn) {
+		h.handle(r, t)
+	}
+}
+
+func (h *handler) handle(r *http.Request, t channel.Conn) {
+	server := channel.NewServer(t, h.channelHub, h.serverOptions...)
 	h.channelHub.Add(server)
 	defer h.channelHub.Remove(server)
 
+	ctx, cancel := context.WithCancel(h.ctx)
+	defer cancel()
+
 	rt := h.setupRoutes()
 	lc := lv.NewLifecycle(rt, h.tokenizer, h.sessionGetter)
 
-	server.Route("lv:*", lvchan.New(lc))
-	server.Route("lvu:*", lvuchan.New(lc))
+	// every message for the liveview is processed on a single goroutine.
+	mb := mailbox.New(ctx)
 
+	routes := map[string]func() channel.Channel{
+		"lv:*":  lvchan.New(lc, mb),
+		"lvu:*": lvuchan.New(lc, mb),
+	}
 	for topic, factory := range h.channels {
+		routes[topic] = factory
+	}
+
+	for topic, factory := range routes {
+		if h.csrf != nil {
+			factory = h.csrf.protect(r, factory, topic == "lv:*")
+		}
+
 		server.Route(topic, factory)
 	}
 
-	server.Listen(h.ctx)
+	server.Listen(ctx)
 }
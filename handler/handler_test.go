@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sethpollack/go-live-view/components"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/router"
+	"github.com/sethpollack/go-live-view/std"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type userSession struct {
+	user string
+}
+
+func (s *userSession) Get(*http.Request) map[string]any {
+	if s.user == "" {
+		return map[string]any{}
+	}
+	return map[string]any{"user": s.user}
+}
+
+func TestStaticOnMount(t *testing.T) {
+	requireUser := func(s lv.Socket, p params.Params, session map[string]any) error {
+		switch session["user"] {
+		case nil:
+			return lv.Halt("/login")
+		case "banned":
+			return errors.New("banned")
+		}
+		return nil
+	}
+
+	tt := []struct {
+		name     string
+		user     string
+		code     int
+		location string
+	}{
+		{name: "continue", user: "bob", code: http.StatusOK},
+		{name: "halt with redirect", code: http.StatusFound, location: "/login"},
+		{name: "halt with error", user: "banned", code: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := NewHandler(context.Background(), func() lv.Router {
+				rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+				rt.Handle("/", &staticLive{}, router.WithSession("admin", requireUser))
+				return rt
+			}, WithSessionGetter(&userSession{user: tc.user}))
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			assert.Equal(t, tc.code, w.Code)
+			assert.Equal(t, tc.location, w.Header().Get("Location"))
+		})
+	}
+}
+
+func TestHttpRoutes(t *testing.T) {
+	h := NewHandler(context.Background(), func() lv.Router {
+		rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+		rt.Handle("/home", &staticLive{})
+		rt.Group("/admin", nil, router.WithMiddleware(func(http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+			})
+		})).Handle("/users", &staticLive{})
+		rt.Get("/api/ping", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("pong"))
+		})
+		return rt
+	})
+
+	tt := []struct {
+		name   string
+		method string
+		path   string
+		code   int
+		body   string
+	}{
+		{name: "view", method: "GET", path: "/home", code: http.StatusOK, body: "<div></div>"},
+		{name: "handler", method: "GET", path: "/api/ping", code: http.StatusOK, body: "pong"},
+		{name: "method not allowed", method: "POST", path: "/api/ping", code: http.StatusMethodNotAllowed},
+		{name: "not found", method: "GET", path: "/missing", code: http.StatusNotFound},
+		{name: "middleware", method: "GET", path: "/admin/users", code: http.StatusForbidden},
+		{name: "extra segments", method: "GET", path: "/admin/users/x", code: http.StatusNotFound},
+		{name: "extra segments deep", method: "GET", path: "/admin/users/bogus/more", code: http.StatusNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+
+			assert.Equal(t, tc.code, w.Code)
+			if tc.body != "" {
+				assert.Contains(t, w.Body.String(), tc.body)
+			}
+		})
+	}
+}
+
+type titledLive struct {
+	staticLive
+	title string
+}
+
+func (l *titledLive) Title() string {
+	return l.title
+}
+
+func TestLiveTitle(t *testing.T) {
+	h := NewHandler(context.Background(), func() lv.Router {
+		rt := router.NewRouter(func(n ...rend.Node) rend.Node {
+			return std.Group(append([]rend.Node{
+				lv.LiveTitle(lv.WithTitleSuffix(" · App"), lv.WithDefaultTitle("Home")),
+			}, n...)...)
+		})
+		rt.Handle("/", &staticLive{})
+		rt.Handle("/users", &titledLive{title: "<Users>"})
+		return rt
+	})
+
+	tt := []struct {
+		path     string
+		expected string
+	}{
+		{path: "/", expected: `<title data-suffix=" · App" data-default="Home">Home · App</title>`},
+		{path: "/users", expected: `<title data-suffix=" · App" data-default="Home">&lt;Users&gt; · App</title>`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tc.path, nil))
+
+			assert.Contains(t, w.Body.String(), tc.expected)
+		})
+	}
+}
+
+func TestStaticFlash(t *testing.T) {
+	h := NewHandler(context.Background(), func() lv.Router {
+		rt := router.NewRouter(func(n ...rend.Node) rend.Node {
+			return std.Group(append([]rend.Node{components.FlashGroup()}, n...)...)
+		})
+		rt.Handle("/", &staticLive{})
+		return rt
+	}, WithSecretKey("secret"))
+
+	signed, err := NewSignedTokenizer("secret").Encode("live_view flash", map[string]any{"info": "Saved"})
+	assert.NoError(t, err)
+
+	forged, err := NewSignedTokenizer("other").Encode("live_view flash", map[string]any{"info": "Forged"})
+	assert.NoError(t, err)
+
+	tt := []struct {
+		name     string
+		cookie   string
+		expected string
+	}{
+		{
+			name:     "signed",
+			cookie:   signed,
+			expected: `<div id="flash-group"><div id="flash-info" class="flash flash-info" role="alert" phx-click="lv:clear-flash" phx-value-key="info">Saved</div></div>`,
+		},
+		{
+			name:     "forged",
+			cookie:   forged,
+			expected: `<div id="flash-group"></div>`,
+		},
+		{
+			name:     "unsigned",
+			cookie:   base64.StdEncoding.EncodeToString([]byte(`{"info":"Forged"}`)),
+			expected: `<div id="flash-group"></div>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// the client stores the flash of a redirect in a cookie.
+			r := httptest.NewRequest("GET", "/", nil)
+			r.AddCookie(&http.Cookie{Name: "__phoenix_flash__", Value: tc.cookie})
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			assert.Contains(t, w.Body.String(), tc.expected)
+
+			cookie := cookieFrom(w, "__phoenix_flash__")
+			if assert.NotNil(t, cookie) {
+				assert.Empty(t, cookie.Value)
+			}
+		})
+	}
+}
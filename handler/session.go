@@ -1,6 +1,43 @@
 package handler
 
-import "net/http"
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	lv "github.com/sethpollack/go-live-view/liveview"
+)
+
+const (
+	defaultSessionCookie = "_live_view_session"
+	defaultSessionPath   = "/session"
+
+	// browsers drop cookies over 4kb.
+	maxCookieSize = 4096
+	// session updates are only followed straight after they are issued.
+	sessionUpdateMaxAge = time.Minute
+
+	cookieSalt = "cookie session"
+	updateSalt = "session update"
+
+	// SessionBindKey is the session key holding the browser's binding,
+	// which ties session updates to the browser they were issued for.
+	SessionBindKey  = "_session_bind"
+	sessionBindSize = 18
+)
+
+type sessionBindContextKey struct{}
+
+// sessionBinder is a sessionGetter that binds pages to the browser,
+// setting a cookie on the page's response.
+type sessionBinder interface {
+	bind(http.ResponseWriter, *http.Request) (*http.Request, error)
+}
 
 type sessionGetter interface {
 	Get(*http.Request) map[string]any
@@ -11,3 +48,384 @@ type defaultSessionGetter struct{}
 func (d *defaultSessionGetter) Get(r *http.Request) map[string]any {
 	return make(map[string]any)
 }
+
+var (
+	_ sessionGetter = (*CookieSession)(nil)
+	_ sessionBinder = (*CookieSession)(nil)
+	_ http.Handler  = (*CookieSession)(nil)
+)
+
+type cookieOption func(*CookieSession)
+
+// CookieSession stores the session in a cookie, encrypted by default so
+// the client can neither read nor alter it.
+//
+// Sockets can't set cookies, so LiveViews change the session by redirecting
+// through the CookieSession's endpoint, which writes the cookie and then
+// redirects on. Mount it at the session path:
+//
+//	sessions := handler.NewCookieSession(secret)
+//	mux.Handle("/session", sessions)
+//	mux.Handle("/", handler.NewHandler(ctx, routes, handler.WithSessionGetter(sessions)))
+//
+// and from an event handler:
+//
+//	return l.Sessions.Redirect(s, "/", map[string]any{"user_id": id})
+//
+// Updates are bound to the browser the page was served to, by a random
+// value kept in a second cookie, and each one can only be applied once.
+type CookieSession struct {
+	name     string
+	path     string
+	domain   string
+	maxAge   time.Duration
+	secure   *bool
+	sameSite http.SameSite
+	endpoint string
+
+	secret     string
+	oldSecrets []string
+	signed     bool
+
+	cookie  tokenizer
+	updates tokenizer
+}
+
+// NewCookieSession returns a session getter backed by a cookie sealed with
+// a key derived from secret. It panics when secret is empty.
+func NewCookieSession(secret string, opts ...cookieOption) *CookieSession {
+	if secret == "" {
+		panic("handler: NewCookieSession needs a secret")
+	}
+
+	c := &CookieSession{
+		secret:   secret,
+		name:     defaultSessionCookie,
+		path:     "/",
+		sameSite: http.SameSiteLaxMode,
+		endpoint: defaultSessionPath,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	cookieOpts := []tokenOption{WithOldSecrets(c.oldSecrets...)}
+	if c.maxAge > 0 {
+		cookieOpts = append(cookieOpts, WithMaxAge(c.maxAge))
+	}
+
+	if c.signed {
+		c.cookie = NewSignedTokenizer(secret, cookieOpts...)
+	} else {
+		c.cookie = NewEncryptedTokenizer(secret, cookieOpts...)
+	}
+
+	// updates travel in the url, so they're always encrypted.
+	c.updates = NewEncryptedTokenizer(secret,
+		WithMaxAge(sessionUpdateMaxAge),
+		WithOldSecrets(c.oldSecrets...),
+	)
+
+	return c
+}
+
+// pageTokenizer seals the tokens put in pages, which carry a copy of the
+// session, the same way as the cookie.
+func (c *CookieSession) pageTokenizer() tokenizer {
+	opts := []tokenOption{WithOldSecrets(c.oldSecrets...)}
+
+	if c.signed {
+		return NewSignedTokenizer(c.secret, opts...)
+	}
+
+	return NewEncryptedTokenizer(c.secret, opts...)
+}
+
+// WithCookieName sets the cookie name. Defaults to "_live_view_session".
+func WithCookieName(name string) cookieOption {
+	return func(c *CookieSession) {
+		c.name = name
+	}
+}
+
+// WithCookiePath sets the cookie path. Defaults to "/".
+func WithCookiePath(path string) cookieOption {
+	return func(c *CookieSession) {
+		c.path = path
+	}
+}
+
+func WithCookieDomain(domain string) cookieOption {
+	return func(c *CookieSession) {
+		c.domain = domain
+	}
+}
+
+// WithCookieMaxAge keeps the session for d. By default the cookie lasts
+// until the browser is closed.
+func WithCookieMaxAge(d time.Duration) cookieOption {
+	return func(c *CookieSession) {
+		c.maxAge = d
+	}
+}
+
+// WithCookieSecure sets the Secure attribute. By default it's set when the
+// request came over TLS.
+func WithCookieSecure(secure bool) cookieOption {
+	return func(c *CookieSession) {
+		c.secure = &secure
+	}
+}
+
+// WithCookieSameSite defaults to http.SameSiteLaxMode.
+func WithCookieSameSite(sameSite http.SameSite) cookieOption {
+	return func(c *CookieSession) {
+		c.sameSite = sameSite
+	}
+}
+
+// WithCookieSigned only signs the session, leaving it readable by the
+// client.
+func WithCookieSigned() cookieOption {
+	return func(c *CookieSession) {
+		c.signed = true
+	}
+}
+
+// WithCookieOldSecrets accepts sessions issued with previous secrets.
+func WithCookieOldSecrets(secrets ...string) cookieOption {
+	return func(c *CookieSession) {
+		c.oldSecrets = append(c.oldSecrets, secrets...)
+	}
+}
+
+// WithSessionPath sets where the session endpoint is mounted. Defaults to
+// "/session".
+func WithSessionPath(path string) cookieOption {
+	return func(c *CookieSession) {
+		c.endpoint = path
+	}
+}
+
+// Get returns the request's session, or an empty one when the cookie is
+// missing, altered or expired. The browser's binding is included under
+// SessionBindKey.
+func (c *CookieSession) Get(r *http.Request) map[string]any {
+	session := c.read(r)
+
+	if bind := c.binding(r); bind != "" {
+		session[SessionBindKey] = bind
+	}
+
+	return session
+}
+
+func (c *CookieSession) read(r *http.Request) map[string]any {
+	session := map[string]any{}
+
+	cookie, err := r.Cookie(c.name)
+	if err != nil {
+		return session
+	}
+
+	err = c.cookie.Decode(cookieSalt, cookie.Value, &session)
+	if err != nil {
+		return map[string]any{}
+	}
+
+	return session
+}
+
+// Put writes session to the response, replacing the current one.
+func (c *CookieSession) Put(w http.ResponseWriter, r *http.Request, session map[string]any) error {
+	value, err := c.cookie.Encode(cookieSalt, session)
+	if err != nil {
+		return err
+	}
+
+	cookie := c.newCookie(r, value)
+	if c.maxAge > 0 {
+		cookie.MaxAge = int(c.maxAge / time.Second)
+	}
+
+	if len(cookie.String()) > maxCookieSize {
+		return fmt.Errorf("session cookie exceeds %d bytes", maxCookieSize)
+	}
+
+	http.SetCookie(w, cookie)
+
+	return nil
+}
+
+// Clear removes the session cookie.
+func (c *CookieSession) Clear(w http.ResponseWriter, r *http.Request) {
+	cookie := c.newCookie(r, "")
+	cookie.MaxAge = -1
+
+	http.SetCookie(w, cookie)
+}
+
+// UpdateURL returns a url for the session endpoint that merges changes
+// into the session and then redirects to to. Keys set to nil are removed.
+// The update only applies in the browser session came from.
+func (c *CookieSession) UpdateURL(session map[string]any, to string, changes map[string]any) (string, error) {
+	bind, _ := session[SessionBindKey].(string)
+	if bind == "" {
+		return "", fmt.Errorf("session has no binding, is the CookieSession set with WithSessionGetter?")
+	}
+
+	token, err := c.updates.Encode(updateSalt, sessionUpdate{
+		To:      to,
+		Changes: changes,
+		Bind:    bind,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return c.endpoint + "?" + url.Values{"token": {token}}.Encode(), nil
+}
+
+// Redirect sends the client through the session endpoint to apply changes,
+// ending up at to.
+func (c *CookieSession) Redirect(s lv.Socket, to string, changes map[string]any) error {
+	u, err := c.UpdateURL(s.Session(), to, changes)
+	if err != nil {
+		return err
+	}
+
+	return s.Redirect(u)
+}
+
+type sessionUpdate struct {
+	To      string         `json:"to"`
+	Changes map[string]any `json:"changes"`
+	Bind    string         `json:"bind"`
+}
+
+// ServeHTTP applies a session update issued by UpdateURL, if it was issued
+// for this browser. The binding is replaced afterwards, so the update
+// can't be applied again.
+func (c *CookieSession) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	update := sessionUpdate{}
+
+	err := c.updates.Decode(updateSalt, r.URL.Query().Get("token"), &update)
+	if err != nil {
+		http.Error(w, "invalid session update", http.StatusBadRequest)
+		return
+	}
+
+	bind := c.binding(r)
+	if bind == "" || subtle.ConstantTimeCompare([]byte(bind), []byte(update.Bind)) != 1 {
+		http.Error(w, "invalid session update", http.StatusBadRequest)
+		return
+	}
+
+	_, err = c.newBinding(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	session := c.read(r)
+	for k, v := range update.Changes {
+		if v == nil {
+			delete(session, k)
+			continue
+		}
+		session[k] = v
+	}
+
+	if len(session) == 0 {
+		c.Clear(w, r)
+	} else if err := c.Put(w, r, session); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, localPath(update.To), http.StatusFound)
+}
+
+// bind makes sure the browser has a binding before a page is rendered,
+// returning r with the binding Get should see.
+func (c *CookieSession) bind(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	if c.binding(r) != "" {
+		return r, nil
+	}
+
+	bind, err := c.newBinding(w, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), sessionBindContextKey{}, bind)), nil
+}
+
+// binding returns the browser's binding, or "" when it has none.
+func (c *CookieSession) binding(r *http.Request) string {
+	if bind, ok := r.Context().Value(sessionBindContextKey{}).(string); ok {
+		return bind
+	}
+
+	cookie, err := r.Cookie(c.bindName())
+	if err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+func (c *CookieSession) newBinding(w http.ResponseWriter, r *http.Request) (string, error) {
+	b := make([]byte, sessionBindSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	bind := base64.RawURLEncoding.EncodeToString(b)
+
+	cookie := c.newCookie(r, bind)
+	cookie.Name = c.bindName()
+	if c.maxAge > 0 {
+		cookie.MaxAge = int(c.maxAge / time.Second)
+	}
+
+	http.SetCookie(w, cookie)
+
+	return bind, nil
+}
+
+func (c *CookieSession) bindName() string {
+	return c.name + "_bind"
+}
+
+func (c *CookieSession) newCookie(r *http.Request, value string) *http.Cookie {
+	secure := r.TLS != nil
+	if c.secure != nil {
+		secure = *c.secure
+	}
+
+	return &http.Cookie{
+		Name:     c.name,
+		Value:    value,
+		Path:     c.path,
+		Domain:   c.domain,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: c.sameSite,
+	}
+}
+
+// localPath keeps redirects on this site.
+func localPath(to string) string {
+	u, err := url.Parse(to)
+	if err != nil || u.IsAbs() || u.Host != "" || len(u.Path) == 0 || u.Path[0] != '/' {
+		return "/"
+	}
+
+	// browsers read "/\host" as "//host".
+	if len(u.Path) > 1 && u.Path[1] == '\\' {
+		return "/"
+	}
+
+	return u.String()
+}
@@ -0,0 +1,316 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/router"
+	"github.com/sethpollack/go-live-view/std"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func cookieFrom(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestCookieSession(t *testing.T) {
+	tt := []struct {
+		name string
+		opts []cookieOption
+	}{
+		{name: "encrypted"},
+		{name: "signed", opts: []cookieOption{WithCookieSigned()}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewCookieSession("secret", tc.opts...)
+
+			w := httptest.NewRecorder()
+			assert.NoError(t, c.Put(w, httptest.NewRequest("GET", "/", nil), map[string]any{"user_id": "1"}))
+
+			cookie := cookieFrom(w, defaultSessionCookie)
+			assert.NotNil(t, cookie)
+			assert.True(t, cookie.HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.AddCookie(cookie)
+			assert.Equal(t, map[string]any{"user_id": "1"}, c.Get(r))
+
+			// tampered
+			r = httptest.NewRequest("GET", "/", nil)
+			r.AddCookie(&http.Cookie{Name: defaultSessionCookie, Value: cookie.Value + "x"})
+			assert.Equal(t, map[string]any{}, c.Get(r))
+
+			// another secret
+			r = httptest.NewRequest("GET", "/", nil)
+			r.AddCookie(cookie)
+			assert.Equal(t, map[string]any{}, NewCookieSession("other", tc.opts...).Get(r))
+		})
+	}
+}
+
+func TestCookieSessionAttributes(t *testing.T) {
+	c := NewCookieSession("secret",
+		WithCookieName("sid"),
+		WithCookiePath("/app"),
+		WithCookieDomain("example.com"),
+		WithCookieMaxAge(time.Hour),
+		WithCookieSecure(true),
+		WithCookieSameSite(http.SameSiteStrictMode),
+	)
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, c.Put(w, httptest.NewRequest("GET", "/", nil), map[string]any{"a": "b"}))
+
+	cookie := cookieFrom(w, "sid")
+	assert.NotNil(t, cookie)
+	assert.Equal(t, "/app", cookie.Path)
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.Equal(t, 3600, cookie.MaxAge)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie.SameSite)
+
+	w = httptest.NewRecorder()
+	err := c.Put(w, httptest.NewRequest("GET", "/", nil), map[string]any{"big": string(make([]byte, maxCookieSize))})
+	assert.Error(t, err)
+	assert.Nil(t, cookieFrom(w, "sid"))
+}
+
+// browser keeps the cookies set on its responses.
+type browser struct {
+	cookies map[string]*http.Cookie
+}
+
+func newBrowser() *browser {
+	return &browser{cookies: map[string]*http.Cookie{}}
+}
+
+func (b *browser) request(target string) *http.Request {
+	r := httptest.NewRequest("GET", target, nil)
+	for _, c := range b.cookies {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func (b *browser) keep(w *httptest.ResponseRecorder) {
+	for _, c := range w.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(b.cookies, c.Name)
+			continue
+		}
+		b.cookies[c.Name] = c
+	}
+}
+
+// load serves a page, returning the session its liveview joins with.
+func (b *browser) load(c *CookieSession) map[string]any {
+	w := httptest.NewRecorder()
+	r, err := c.bind(w, b.request("/"))
+	if err != nil {
+		panic(err)
+	}
+	b.keep(w)
+
+	return c.Get(r)
+}
+
+func (b *browser) follow(c *CookieSession, u string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, b.request(u))
+	b.keep(w)
+	return w
+}
+
+func TestCookieSessionUpdate(t *testing.T) {
+	c := NewCookieSession("secret")
+	b := newBrowser()
+
+	// login
+	u, err := c.UpdateURL(b.load(c), "/account?tab=1", map[string]any{"user_id": "1", "theme": "dark"})
+	assert.NoError(t, err)
+
+	w := b.follow(c, u)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/account?tab=1", w.Header().Get("Location"))
+	assert.Equal(t, map[string]any{"user_id": "1", "theme": "dark"}, c.read(b.request("/")))
+
+	// logout keeps the rest of the session
+	u, err = c.UpdateURL(b.load(c), "/", map[string]any{"user_id": nil})
+	assert.NoError(t, err)
+
+	w = b.follow(c, u)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, map[string]any{"theme": "dark"}, c.read(b.request("/")))
+
+	// removing everything clears the cookie
+	u, err = c.UpdateURL(b.load(c), "/", map[string]any{"theme": nil})
+	assert.NoError(t, err)
+
+	w = b.follow(c, u)
+	assert.Equal(t, -1, cookieFrom(w, defaultSessionCookie).MaxAge)
+
+	// forged updates are rejected
+	w = b.follow(c, "/session?"+url.Values{"token": {"forged"}}.Encode())
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, cookieFrom(w, defaultSessionCookie))
+}
+
+func TestCookieSessionUpdateBinding(t *testing.T) {
+	c := NewCookieSession("secret")
+
+	attacker := newBrowser()
+	u, err := c.UpdateURL(attacker.load(c), "/", map[string]any{"user_id": "attacker"})
+	assert.NoError(t, err)
+
+	// planted in another browser, with or without a binding of its own.
+	victim := newBrowser()
+	w := victim.follow(c, u)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, cookieFrom(w, defaultSessionCookie))
+
+	victim.load(c)
+	w = victim.follow(c, u)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, cookieFrom(w, defaultSessionCookie))
+
+	// applied once in the browser it was issued for.
+	w = attacker.follow(c, u)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	w = attacker.follow(c, u)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, cookieFrom(w, defaultSessionCookie))
+
+	// sessions without a binding can't issue updates.
+	_, err = c.UpdateURL(map[string]any{}, "/", nil)
+	assert.Error(t, err)
+}
+
+func TestLocalPath(t *testing.T) {
+	tt := []struct {
+		to   string
+		want string
+	}{
+		{to: "/account", want: "/account"},
+		{to: "/account?tab=1#top", want: "/account?tab=1#top"},
+		{to: "", want: "/"},
+		{to: "account", want: "/"},
+		{to: "https://evil.com/", want: "/"},
+		{to: "//evil.com/", want: "/"},
+		{to: `/\evil.com`, want: "/"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.to, func(t *testing.T) {
+			assert.Equal(t, tc.want, localPath(tc.to))
+		})
+	}
+}
+
+func TestCookieSessionBindsPages(t *testing.T) {
+	sessions := NewCookieSession("secret")
+
+	var mounted map[string]any
+	h := NewHandler(context.Background(), func() lv.Router {
+		rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+		rt.Handle("/", &staticLive{}, router.WithSession("default", func(s lv.Socket, p params.Params, session map[string]any) error {
+			mounted = session
+			return nil
+		}))
+		return rt
+	}, WithSessionGetter(sessions))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	cookie := cookieFrom(w, defaultSessionCookie+"_bind")
+	if assert.NotNil(t, cookie) {
+		assert.True(t, cookie.HttpOnly)
+		assert.Equal(t, cookie.Value, mounted[SessionBindKey])
+	}
+}
+
+// pageToken is the page's session token.
+type pageToken struct {
+	Session map[string]any `json:"session"`
+	Path    string         `json:"path"`
+}
+
+var sessionRe = regexp.MustCompile(`data-phx-session="([^"]+)"`)
+
+func TestCookieSessionSealsPages(t *testing.T) {
+	tt := []struct {
+		name   string
+		opts   []cookieOption
+		header string
+	}{
+		{name: "encrypted", header: encryptedHeader},
+		{name: "signed", opts: []cookieOption{WithCookieSigned()}, header: signedHeader},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions := NewCookieSession("secret", tc.opts...)
+			b := newBrowser()
+			w := httptest.NewRecorder()
+			assert.NoError(t, sessions.Put(w, b.request("/"), map[string]any{"user_id": "1"}))
+			b.keep(w)
+
+			// the page's session token is sealed like the cookie, even
+			// without WithSecretKey.
+			h := NewHandler(context.Background(), func() lv.Router {
+				rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+				rt.Handle("/", &staticLive{})
+				return rt
+			}, WithSessionGetter(sessions))
+
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, b.request("/"))
+
+			m := sessionRe.FindStringSubmatch(w.Body.String())
+			if assert.Len(t, m, 2) {
+				assert.True(t, strings.HasPrefix(m[1], tc.header+"."), m[1])
+				assert.NotContains(t, w.Body.String(), `"user_id"`)
+			}
+
+			decoded := pageToken{}
+			assert.NoError(t, h.tokenizer.Decode("live_view session", m[1], &decoded))
+			assert.Equal(t, "1", decoded.Session["user_id"])
+			assert.Equal(t, "/", decoded.Path)
+
+			forged, err := (&defaultTokenizer{}).Encode("live_view session", map[string]any{
+				"session": map[string]any{"user_id": "2"},
+				"path":    "/",
+			})
+			assert.NoError(t, err)
+			assert.ErrorIs(t, h.tokenizer.Decode("live_view session", forged, &decoded), lv.ErrInvalidToken)
+		})
+	}
+}
+
+func TestEmptySecret(t *testing.T) {
+	assert.PanicsWithValue(t, "handler: NewCookieSession needs a secret", func() {
+		NewCookieSession("")
+	})
+	assert.PanicsWithValue(t, "handler: WithSecretKey needs a secret", func() {
+		WithSecretKey("")
+	})
+}
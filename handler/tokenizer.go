@@ -1,18 +1,38 @@
 package handler
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	lv "github.com/sethpollack/go-live-view/liveview"
+)
+
+const (
+	defaultTokenMaxAge = 14 * 24 * time.Hour
+
+	// base64url of the algorithm names put in front of tokens.
+	signedHeader    = "SFMyNTY"    // HS256
+	encryptedHeader = "QTI1NkdDTQ" // A256GCM
 )
 
+// tokenizer seals values for a purpose named by salt, so a token issued for
+// one purpose doesn't verify as another.
 type tokenizer interface {
-	Encode(any) (string, error)
-	Decode(string, any) error
+	Encode(salt string, v any) (string, error)
+	Decode(salt, token string, v any) error
 }
 
 type defaultTokenizer struct{}
 
-func (d *defaultTokenizer) Encode(v any) (string, error) {
+func (d *defaultTokenizer) Encode(_ string, v any) (string, error) {
 	b, err := json.Marshal(v)
 	if err != nil {
 		return "", err
@@ -21,7 +41,7 @@ func (d *defaultTokenizer) Encode(v any) (string, error) {
 	return base64.StdEncoding.EncodeToString(b), nil
 }
 
-func (d *defaultTokenizer) Decode(s string, v any) error {
+func (d *defaultTokenizer) Decode(_, s string, v any) error {
 	b, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
 		return err
@@ -29,3 +49,284 @@ func (d *defaultTokenizer) Decode(s string, v any) error {
 
 	return json.Unmarshal(b, v)
 }
+
+type tokenOption func(*tokenConfig)
+
+type tokenConfig struct {
+	secrets []string
+	maxAge  time.Duration
+	now     func() time.Time
+
+	mu   sync.Mutex
+	keys map[string][][]byte
+}
+
+// WithMaxAge sets how long tokens are valid for. Defaults to two weeks.
+func WithMaxAge(d time.Duration) tokenOption {
+	return func(c *tokenConfig) {
+		c.maxAge = d
+	}
+}
+
+// WithOldSecrets accepts tokens issued with previous secrets while new
+// tokens are only issued with the current one.
+func WithOldSecrets(secrets ...string) tokenOption {
+	return func(c *tokenConfig) {
+		c.secrets = append(c.secrets, secrets...)
+	}
+}
+
+func newTokenConfig(secret string, opts []tokenOption) *tokenConfig {
+	c := &tokenConfig{
+		secrets: []string{secret},
+		maxAge:  defaultTokenMaxAge,
+		now:     time.Now,
+		keys:    map[string][][]byte{},
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// saltedKeys returns the keys for salt, current secret first. Deriving them
+// is slow, so they're kept.
+func (c *tokenConfig) saltedKeys(salt string) [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys, ok := c.keys[salt]
+	if !ok {
+		for _, s := range c.secrets {
+			keys = append(keys, deriveKey(s, salt))
+		}
+		c.keys[salt] = keys
+	}
+
+	return keys
+}
+
+// payload is the term Phoenix.Token signs: {data, signed_at, max_age}, with
+// signed_at in milliseconds and max_age in seconds.
+func (c *tokenConfig) payload(v any) ([]byte, error) {
+	data, err := jsonTerm(v)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalTerm(tuple{
+		data,
+		c.now().UnixMilli(),
+		int64(c.maxAge / time.Second),
+	})
+}
+
+// unpack reads a payload into v. Tokens expire by the max age of the
+// tokenizer decoding them, not the one they were issued with.
+func (c *tokenConfig) unpack(b []byte, v any) error {
+	t, err := unmarshalTerm(b)
+	if err != nil {
+		return lv.ErrInvalidToken
+	}
+
+	p, ok := t.(tuple)
+	if !ok || len(p) != 3 {
+		return lv.ErrInvalidToken
+	}
+
+	signed, ok := p[1].(int64)
+	if !ok {
+		return lv.ErrInvalidToken
+	}
+
+	expires := time.UnixMilli(signed).Add(c.maxAge)
+	if c.now().After(expires) {
+		return lv.ErrExpiredToken
+	}
+
+	data, err := json.Marshal(p[0])
+	if err != nil {
+		return lv.ErrInvalidToken
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return lv.ErrInvalidToken
+	}
+
+	return nil
+}
+
+type signedTokenizer struct {
+	config *tokenConfig
+}
+
+// NewSignedTokenizer returns a tokenizer whose tokens are readable by the
+// client but can't be forged or altered. Tokens are Phoenix.Token's, signed
+// with HMAC-SHA256 using a key derived from secret and the salt, so
+// Phoenix.Token.verify reads them and tokens from Phoenix.Token.sign decode,
+// as long as their data is made of maps, lists, binaries and numbers.
+func NewSignedTokenizer(secret string, opts ...tokenOption) *signedTokenizer {
+	return &signedTokenizer{
+		config: newTokenConfig(secret, opts),
+	}
+}
+
+func (t *signedTokenizer) Encode(salt string, v any) (string, error) {
+	b, err := t.config.payload(v)
+	if err != nil {
+		return "", err
+	}
+
+	plain := signedHeader + "." + encode64(b)
+
+	return plain + "." + encode64(sign(t.config.saltedKeys(salt)[0], plain)), nil
+}
+
+func (t *signedTokenizer) Decode(salt, s string, v any) error {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 || parts[0] != signedHeader {
+		return lv.ErrInvalidToken
+	}
+
+	sig, err := decode64(parts[2])
+	if err != nil {
+		return lv.ErrInvalidToken
+	}
+
+	plain := parts[0] + "." + parts[1]
+
+	for _, key := range t.config.saltedKeys(salt) {
+		if !hmac.Equal(sig, sign(key, plain)) {
+			continue
+		}
+
+		b, err := decode64(parts[1])
+		if err != nil {
+			return lv.ErrInvalidToken
+		}
+
+		return t.config.unpack(b, v)
+	}
+
+	return lv.ErrInvalidToken
+}
+
+type encryptedTokenizer struct {
+	config *tokenConfig
+}
+
+// NewEncryptedTokenizer returns a tokenizer whose tokens can't be read or
+// altered by the client. Tokens seal the same payload as Phoenix.Token with
+// AES-256-GCM, using a key derived from secret and the salt, but not in
+// Phoenix.Token.encrypt's format.
+func NewEncryptedTokenizer(secret string, opts ...tokenOption) *encryptedTokenizer {
+	return &encryptedTokenizer{
+		config: newTokenConfig(secret, opts),
+	}
+}
+
+func (t *encryptedTokenizer) Encode(salt string, v any) (string, error) {
+	b, err := t.config.payload(v)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(t.config.saltedKeys(salt)[0])
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nil, nonce, b, []byte(encryptedHeader))
+
+	return encryptedHeader + "." + encode64(nonce) + "." + encode64(sealed), nil
+}
+
+func (t *encryptedTokenizer) Decode(salt, s string, v any) error {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 || parts[0] != encryptedHeader {
+		return lv.ErrInvalidToken
+	}
+
+	nonce, err := decode64(parts[1])
+	if err != nil {
+		return lv.ErrInvalidToken
+	}
+
+	sealed, err := decode64(parts[2])
+	if err != nil {
+		return lv.ErrInvalidToken
+	}
+
+	for _, key := range t.config.saltedKeys(salt) {
+		aead, err := newAEAD(key)
+		if err != nil {
+			return err
+		}
+
+		if len(nonce) != aead.NonceSize() {
+			return lv.ErrInvalidToken
+		}
+
+		b, err := aead.Open(nil, nonce, sealed, []byte(encryptedHeader))
+		if err != nil {
+			continue
+		}
+
+		return t.config.unpack(b, v)
+	}
+
+	return lv.ErrInvalidToken
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// deriveKey is PBKDF2-HMAC-SHA256 with 1000 iterations and a 32 byte key,
+// the same as Plug.Crypto.KeyGenerator's defaults.
+func deriveKey(secret, salt string) []byte {
+	prf := hmac.New(sha256.New, []byte(secret))
+	prf.Write([]byte(salt))
+	prf.Write([]byte{0, 0, 0, 1})
+
+	u := prf.Sum(nil)
+	key := append([]byte{}, u...)
+
+	for i := 1; i < 1000; i++ {
+		prf.Reset()
+		prf.Write(u)
+		u = prf.Sum(u[:0])
+
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+
+	return key
+}
+
+func sign(key []byte, plain string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(plain))
+	return mac.Sum(nil)
+}
+
+func encode64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
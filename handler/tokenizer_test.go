@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	lv "github.com/sethpollack/go-live-view/liveview"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizers(t *testing.T) {
+	tt := []struct {
+		name string
+		new  func(string, ...tokenOption) tokenizer
+	}{
+		{
+			name: "signed",
+			new: func(secret string, opts ...tokenOption) tokenizer {
+				return NewSignedTokenizer(secret, opts...)
+			},
+		},
+		{
+			name: "encrypted",
+			new: func(secret string, opts ...tokenOption) tokenizer {
+				return NewEncryptedTokenizer(secret, opts...)
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Now()
+			clock := func(c *tokenConfig) {
+				c.now = func() time.Time { return now }
+			}
+
+			tok := tc.new("secret", clock, WithMaxAge(time.Hour))
+
+			token, err := tok.Encode("salt", map[string]any{"user_id": "1"})
+			assert.NoError(t, err)
+
+			decoded := map[string]any{}
+			assert.NoError(t, tok.Decode("salt", token, &decoded))
+			assert.Equal(t, map[string]any{"user_id": "1"}, decoded)
+
+			// tampered
+			payload, err := newTokenConfig("secret", nil).payload(map[string]any{"user_id": "2"})
+			assert.NoError(t, err)
+			parts := strings.Split(token, ".")
+			parts[1] = encode64(payload)
+			assert.ErrorIs(t, tok.Decode("salt", strings.Join(parts, "."), &decoded), lv.ErrInvalidToken)
+			assert.ErrorIs(t, tok.Decode("salt", "garbage", &decoded), lv.ErrInvalidToken)
+
+			// wrong secret or salt
+			assert.ErrorIs(t, tc.new("other", clock).Decode("salt", token, &decoded), lv.ErrInvalidToken)
+			assert.ErrorIs(t, tok.Decode("other", token, &decoded), lv.ErrInvalidToken)
+
+			// rotated secret
+			rotated := tc.new("new secret", clock, WithOldSecrets("secret"))
+			assert.NoError(t, rotated.Decode("salt", token, &decoded))
+
+			newToken, err := rotated.Encode("salt", decoded)
+			assert.NoError(t, err)
+			assert.ErrorIs(t, tok.Decode("salt", newToken, &decoded), lv.ErrInvalidToken)
+
+			// the decoder's max age applies, whatever the token was issued with
+			assert.NoError(t, tc.new("secret", clock, WithMaxAge(3*time.Hour)).Decode("salt", token, &decoded))
+
+			// expired
+			now = now.Add(2 * time.Hour)
+			assert.ErrorIs(t, tok.Decode("salt", token, &decoded), lv.ErrExpiredToken)
+			assert.NoError(t, tc.new("secret", clock, WithMaxAge(3*time.Hour)).Decode("salt", token, &decoded))
+		})
+	}
+}
+
+// phoenixToken is Phoenix.Token.sign("secret", "live_view session",
+// %{"user_id" => "1"}, signed_at: 1_700_000_000, max_age: 86_400).
+const phoenixToken = "SFMyNTY.g2gDdAAAAAFtAAAAB3VzZXJfaWRtAAAAATFuBgAAaOXPiwFiAAFRgA.effw8jKqaoPgmg8RjfyJtllgyJygt0IU_Wc76P-xKRU"
+
+func TestPhoenixToken(t *testing.T) {
+	signedAt := time.UnixMilli(1_700_000_000_000)
+	tok := NewSignedTokenizer("secret", WithMaxAge(24*time.Hour), func(c *tokenConfig) {
+		c.now = func() time.Time { return signedAt }
+	})
+
+	token, err := tok.Encode("live_view session", map[string]any{"user_id": "1"})
+	assert.NoError(t, err)
+	assert.Equal(t, phoenixToken, token)
+
+	decoded := map[string]any{}
+	assert.NoError(t, tok.Decode("live_view session", phoenixToken, &decoded))
+	assert.Equal(t, map[string]any{"user_id": "1"}, decoded)
+}
+
+func TestSecretKeyDefault(t *testing.T) {
+	h := NewHandler(context.Background(), nil, WithSecretKey("secret"))
+	assert.IsType(t, &signedTokenizer{}, h.tokenizer)
+
+	h = NewHandler(context.Background(), nil)
+	assert.IsType(t, &defaultTokenizer{}, h.tokenizer)
+}
@@ -19,30 +19,29 @@ func Attr(tagName string, value ...any) rend.Node {
 }
 
 func (attr *attribute) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Builder) error {
+	value := rend.EscapeAttr(attr.value)
+
 	if !diff || !attr.dynamic {
 		if attr.value == "" {
 			_, err := b.Write([]byte(fmt.Sprintf(" %s", attr.tag)))
 			return err
 		}
-		_, err := b.Write([]byte(fmt.Sprintf(" %s=\"%s\"", attr.tag, attr.value)))
+		_, err := b.Write([]byte(fmt.Sprintf(" %s=\"%s\"", attr.tag, value)))
 		return err
 	}
 
-	_, err := b.Write([]byte(fmt.Sprintf(" %s=", attr.tag)))
+	// quotes stay in the statics so the dynamic is only the escaped value.
+	_, err := b.Write([]byte(fmt.Sprintf(" %s=\"", attr.tag)))
 	if err != nil {
 		return err
 	}
 
-	if attr.value == "" {
-		t.AddDynamic("\"\"")
-	} else {
-		t.AddDynamic(attr.value)
-	}
-
+	t.AddDynamic(value)
 	t.AddStatic(b.String())
 	b.Reset()
 
-	return nil
+	_, err = b.Write([]byte("\""))
+	return err
 }
 
 func tag(tag string, value ...any) rend.Node {
@@ -15,6 +15,7 @@ var (
 	update        = flag.Bool("update", false, "update .json files")
 	dynamicText   = "hello"
 	dynamicNumber = 123
+	xssPayload    = `"><script>alert('xss')</script>`
 )
 
 func TestNode(t *testing.T) {
@@ -87,6 +88,18 @@ func TestNode(t *testing.T) {
 						Attr("attr"),
 					),
 				},
+				{
+					name: "escaped static attribute",
+					node: Div(
+						Attr("title", xssPayload),
+					),
+				},
+				{
+					name: "escaped dynamic attribute",
+					node: Div(
+						Attr("title", &xssPayload),
+					),
+				},
 				{
 					name: "values helper",
 					node: Div(
@@ -160,6 +173,35 @@ func TestNode(t *testing.T) {
 	}
 }
 
+func TestEscape(t *testing.T) {
+	tt := []struct {
+		name     string
+		node     rend.Node
+		expected string
+	}{
+		{
+			name:     "static attribute",
+			node:     Div(Attr("title", xssPayload)),
+			expected: `<div title="&#34;&gt;&lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt;"></div>`,
+		},
+		{
+			name:     "dynamic attribute",
+			node:     Div(Attr("title", &xssPayload)),
+			expected: `<div title="&#34;&gt;&lt;script&gt;alert(&#39;xss&#39;)&lt;/script&gt;"></div>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, rend.RenderString(tc.node))
+
+			json := rend.RenderJSON(tc.node)
+			assert.NotContains(t, json, "<script>")
+			assert.Contains(t, json, "&#34;&gt;&lt;script&gt;")
+		})
+	}
+}
+
 func actualValue(t *testing.T, path string, actual string, update bool) string {
 	t.Helper()
 
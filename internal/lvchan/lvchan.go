@@ -5,6 +5,7 @@ import (
 	"net/http"
 
 	"github.com/sethpollack/go-live-view/channel"
+	"github.com/sethpollack/go-live-view/internal/mailbox"
 	lv "github.com/sethpollack/go-live-view/liveview"
 	"github.com/sethpollack/go-live-view/params"
 	"github.com/sethpollack/go-live-view/rend"
@@ -17,46 +18,78 @@ type lifecycle interface {
 	Leave() error
 	StaticRender(http.ResponseWriter, *http.Request) (string, error)
 	Event(lv.Socket, params.Params) (*rend.Root, error)
+	Info(lv.Socket, any) (*rend.Root, error)
 	Params(lv.Socket, params.Params) (*rend.Root, error)
 	AllowUpload(lv.Socket, params.Params) (any, error)
 	Progress(lv.Socket, params.Params) (*rend.Root, error)
-	DestroyCIDs([]int) error
+	WillDestroyCIDs([]int) error
+	DestroyCIDs([]int) ([]int, error)
 }
 
+// lvChannel runs every callback through the mailbox so the lifecycle and
+// its views only ever see one message at a time.
 type lvChannel struct {
 	lc lifecycle
+	mb *mailbox.Mailbox
 }
 
-func New(lc lifecycle) func() channel.Channel {
+func New(lc lifecycle, mb *mailbox.Mailbox) func() channel.Channel {
 	return func() channel.Channel {
 		return &lvChannel{
 			lc: lc,
+			mb: mb,
 		}
 	}
 }
 
 func (l *lvChannel) Join(s channel.Socket, p any) error {
-	rend, err := l.lc.Join(lv.NewSocket(s), params.FromAny(p))
-	if err != nil {
-		return err
-	}
+	return l.mb.Call(func() error {
+		rend, err := l.lc.Join(lv.NewSocket(s), params.FromAny(p))
+		if err != nil {
+			return err
+		}
 
-	return s.Push("", map[string]any{
-		"rendered":         rend,
-		"liveview_version": lv.Version,
+		return s.Push("", map[string]any{
+			"rendered":         rend,
+			"liveview_version": lv.Version,
+		})
 	})
 }
 
 func (l *lvChannel) Leave(s channel.Socket) error {
-	err := l.lc.Leave()
-	if err != nil {
-		return err
-	}
+	return l.mb.Call(func() error {
+		err := l.lc.Leave()
+		if err != nil {
+			return err
+		}
 
-	return s.Push("", nil)
+		return s.Push("", nil)
+	})
 }
 
 func (l *lvChannel) Message(s channel.Socket, event string, p any) error {
+	return l.mb.Call(func() error {
+		return l.message(s, event, p)
+	})
+}
+
+// Broadcast is queued rather than awaited since it is often sent from
+// inside the mailbox, e.g. PushSelf from an event handler.
+func (l *lvChannel) Broadcast(s channel.Socket, event string, p any) error {
+	ok := l.mb.Cast(func() {
+		err := l.broadcast(s, event, p)
+		if err != nil {
+			fmt.Println(err)
+		}
+	})
+	if !ok {
+		return mailbox.ErrClosed
+	}
+
+	return nil
+}
+
+func (l *lvChannel) message(s channel.Socket, event string, p any) error {
 	params := params.FromAny(p)
 
 	switch event {
@@ -68,23 +101,30 @@ func (l *lvChannel) Message(s channel.Socket, event string, p any) error {
 		return l.handleAllowUploadEvent(s, params)
 	case "progress":
 		return l.handleProgressEvent(s, params)
-	case "cids_destroyed", "cids_will_destroy":
+	case "cids_will_destroy":
+		return l.handleWillDestroyCidsEvent(s, params)
+	case "cids_destroyed":
 		return l.handleDestroyCidsEvent(s, params)
 	default:
 		return fmt.Errorf("unhandled event: %s", event)
 	}
 }
 
-func (l *lvChannel) Broadcast(s channel.Socket, event string, p any) error {
-	params := params.FromAny(p)
-
+func (l *lvChannel) broadcast(s channel.Socket, event string, p any) error {
 	switch event {
-	case "event":
-		return l.handleEvent(s, params)
+	case "info":
+		return l.handleInfo(s, decodeInfo(p))
 	case "live_patch":
-		return l.handleLivePatchEvent(s, params)
+		return l.handleLivePatchEvent(s, params.FromAny(p))
+	case "push_events":
+		// events pushed outside of a render.
+		return s.Push("diff", map[string]any{"e": p})
 	default:
-		return fmt.Errorf("unhandled event: %s", event)
+		// broadcasts published outside of a liveview, e.g. presence diffs.
+		return l.handleInfo(s, lv.Info{
+			Event:   event,
+			Payload: p,
+		})
 	}
 }
 
@@ -96,15 +136,37 @@ func (l *lvChannel) handleEvent(s channel.Socket, p params.Params) error {
 	return s.Push("diff", diff)
 }
 
-func (l *lvChannel) handleDestroyCidsEvent(s channel.Socket, p params.Params) error {
-	cids := p.IntSlice("cids")
+func (l *lvChannel) handleInfo(s channel.Socket, msg any) error {
+	diff, err := l.lc.Info(lv.NewSocket(s), msg)
+	if err != nil {
+		return err
+	}
+
+	if diff == nil {
+		return nil
+	}
+
+	return s.Push("diff", diff)
+}
+
+func (l *lvChannel) handleWillDestroyCidsEvent(s channel.Socket, p params.Params) error {
+	err := l.lc.WillDestroyCIDs(p.IntSlice("cids"))
+	if err != nil {
+		return err
+	}
+
+	return s.Push("", nil)
+}
 
-	err := l.lc.DestroyCIDs(cids)
+func (l *lvChannel) handleDestroyCidsEvent(s channel.Socket, p params.Params) error {
+	cids, err := l.lc.DestroyCIDs(p.IntSlice("cids"))
 	if err != nil {
 		return err
 	}
 
-	return s.Push("", cids)
+	return s.Push("", map[string]any{
+		"cids": cids,
+	})
 }
 
 func (l *lvChannel) handleLivePatchEvent(s channel.Socket, p params.Params) error {
@@ -133,3 +195,19 @@ func (l *lvChannel) handleProgressEvent(s channel.Socket, p params.Params) error
 
 	return s.Push("diff", payload)
 }
+
+// decodeInfo restores an Info that was JSON encoded by a remote node.
+func decodeInfo(p any) any {
+	m, ok := p.(map[string]any)
+	if !ok {
+		return p
+	}
+
+	info := lv.Info{
+		Payload: m["payload"],
+	}
+	info.Topic, _ = m["topic"].(string)
+	info.Event, _ = m["event"].(string)
+
+	return info
+}
@@ -0,0 +1,1066 @@
+package lvchan
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"maps"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sethpollack/go-live-view/channel"
+	"github.com/sethpollack/go-live-view/components"
+	"github.com/sethpollack/go-live-view/form"
+	"github.com/sethpollack/go-live-view/html"
+	"github.com/sethpollack/go-live-view/internal/mailbox"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/router"
+	"github.com/sethpollack/go-live-view/std"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _ channel.Socket = (*testSocket)(nil)
+
+type testSocket struct {
+	mu       sync.Mutex
+	pushes   []string
+	payloads []any
+	ch       channel.Channel
+}
+
+func (s *testSocket) ID() string                              { return "test" }
+func (s *testSocket) PushBroadcast(string, string, any) error { return nil }
+func (s *testSocket) Subscribe(string) error                  { return nil }
+func (s *testSocket) Unsubscribe(string) error                { return nil }
+func (s *testSocket) OnClose(func())                          {}
+func (s *testSocket) Close() error                            { return nil }
+
+func (s *testSocket) Push(event string, payload any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pushes = append(s.pushes, event)
+	s.payloads = append(s.payloads, payload)
+	return nil
+}
+
+func (s *testSocket) last() any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.payloads[len(s.payloads)-1]
+}
+
+func (s *testSocket) PushSelf(event string, payload any) error {
+	return s.ch.Broadcast(s, event, payload)
+}
+
+func (s *testSocket) count(event string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, e := range s.pushes {
+		if e == event {
+			n++
+		}
+	}
+	return n
+}
+
+type counterLive struct {
+	count int
+	infos []any
+}
+
+func (l *counterLive) Event(s lv.Socket, event string, _ params.Params) error {
+	l.count++
+	return nil
+}
+
+func (l *counterLive) Info(s lv.Socket, msg any) error {
+	l.infos = append(l.infos, msg)
+	l.count++
+	return nil
+}
+
+func (l *counterLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(std.Text(&l.count)), nil
+}
+
+func TestConcurrentMessages(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+	view := &counterLive{}
+	rt.Handle("/", view)
+
+	lc := lv.NewLifecycle(rt, nil, nil)
+	ch := New(lc, mailbox.New(ctx))()
+	s := &testSocket{ch: ch}
+
+	assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/"}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		// client events arrive from the connection's read loop
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "inc"}))
+		}()
+		// self and hub broadcasts arrive from arbitrary goroutines
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, s.PushSelf("info", lv.Info{Event: "inc"}))
+		}()
+	}
+	wg.Wait()
+
+	assert.Eventually(t, func() bool {
+		return s.count("diff") == 100
+	}, time.Second, time.Millisecond)
+}
+
+func TestInfo(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+	view := &counterLive{}
+	rt.Handle("/", view)
+
+	lc := lv.NewLifecycle(rt, nil, nil)
+	mb := mailbox.New(ctx)
+	ch := New(lc, mb)()
+	s := &testSocket{ch: ch}
+
+	assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/"}))
+
+	type tick struct{ n int }
+
+	// in process messages keep their Go type
+	assert.NoError(t, s.PushSelf("info", lv.Info{Event: "tick", Payload: tick{1}}))
+	// messages from remote nodes arrive JSON decoded
+	assert.NoError(t, ch.Broadcast(s, "info", map[string]any{
+		"topic": "room", "event": "joined", "payload": "bob",
+	}))
+	// broadcasts from plain channel sockets, e.g. presence
+	assert.NoError(t, ch.Broadcast(s, "presence_diff", "diff"))
+
+	// clients can't reach Info
+	assert.Error(t, ch.Message(s, "info", map[string]any{"event": "tick"}))
+
+	assert.NoError(t, mb.Call(func() error { return nil }))
+
+	assert.Equal(t, []any{
+		lv.Info{Event: "tick", Payload: tick{1}},
+		lv.Info{Topic: "room", Event: "joined", Payload: "bob"},
+		lv.Info{Event: "presence_diff", Payload: "diff"},
+	}, view.infos)
+	assert.Equal(t, 3, s.count("diff"))
+}
+
+type parentLive struct {
+	labels []string
+}
+
+func (l *parentLive) Event(s lv.Socket, event string, p params.Params) error {
+	if event == "set" {
+		l.labels = p.Map("value").StringSlice("labels")
+	}
+	return nil
+}
+
+func (l *parentLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(
+		std.Range(l.labels, func(label string) rend.Node {
+			return lv.LiveComponent(label, &counterComponent{Label: label})
+		}),
+	), nil
+}
+
+var componentLog []string
+
+type counterComponent struct {
+	Label string
+	Count int
+}
+
+func (c *counterComponent) Mount(s lv.Socket, _ params.Params) error {
+	componentLog = append(componentLog, "mount "+c.Label)
+	return nil
+}
+
+func (c *counterComponent) Update(s lv.Socket, next any) error {
+	componentLog = append(componentLog, "update "+c.Label)
+	return nil
+}
+
+func (c *counterComponent) Unmount() error {
+	componentLog = append(componentLog, "unmount "+c.Label)
+	return nil
+}
+
+func (c *counterComponent) Event(s lv.Socket, event string, _ params.Params) error {
+	c.Count++
+	return nil
+}
+
+func (c *counterComponent) Render(_ rend.Node) (rend.Node, error) {
+	componentLog = append(componentLog, "render "+c.Label)
+	return html.Button(
+		lv.Myself(),
+		std.Text(&c.Count),
+	), nil
+}
+
+func TestLiveComponents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+	rt.Handle("/", &parentLive{labels: []string{"a", "b"}})
+
+	lc := lv.NewLifecycle(rt, nil, nil)
+	ch := New(lc, mailbox.New(ctx))()
+	s := &testSocket{ch: ch}
+
+	componentLog = nil
+
+	assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/"}))
+	assert.Equal(t, []string{"mount a", "render a", "mount b", "render b"}, componentLog)
+
+	joined := s.last().(map[string]any)["rendered"].(*rend.Root)
+	assert.JSONEq(t, `{
+		"0": {"k": {"0": {"0": 1}, "1": {"0": 2}, "kc": 2}, "s": ["", ""], "f": ""},
+		"s": ["<div>", "</div>"],
+		"f": "",
+		"c": {
+			"1": {"0": "0", "s": ["<button phx-target=\"1\">", "</button>"], "r": true, "f": ""},
+			"2": {"0": "0", "s": ["<button phx-target=\"2\">", "</button>"], "r": true, "f": ""}
+		}
+	}`, stripFingerprints(t, joined))
+
+	// targeted events only re-render the component
+	componentLog = nil
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "inc", "cid": 2}))
+	assert.Equal(t, []string{"render b"}, componentLog)
+	assert.JSONEq(t, `{"c": {"2": {"0": "1"}}}`, rend.RenderJSONTree(s.last().(*rend.Root)))
+
+	// parent renders leave unchanged components alone
+	componentLog = nil
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "noop"}))
+	assert.Nil(t, componentLog)
+	assert.JSONEq(t, `{}`, rend.RenderJSONTree(s.last().(*rend.Root)))
+
+	// removed components are destroyed once the client confirms
+	componentLog = nil
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "set", "value": map[string]any{"labels": []any{"a"}}}))
+	assert.NoError(t, ch.Message(s, "cids_will_destroy", map[string]any{"cids": []any{2}}))
+	assert.NoError(t, ch.Message(s, "cids_destroyed", map[string]any{"cids": []any{2}}))
+	assert.Equal(t, map[string]any{"cids": []int{2}}, s.last())
+	assert.Equal(t, []string{"unmount b"}, componentLog)
+
+	// components rendered again before being destroyed are kept
+	componentLog = nil
+	assert.NoError(t, ch.Message(s, "cids_will_destroy", map[string]any{"cids": []any{1}}))
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "noop"}))
+	assert.NoError(t, ch.Message(s, "cids_destroyed", map[string]any{"cids": []any{1}}))
+	assert.Equal(t, map[string]any{"cids": []int{}}, s.last())
+	assert.Nil(t, componentLog)
+
+	// a new component gets a fresh cid
+	componentLog = nil
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "set", "value": map[string]any{"labels": []any{"a", "b"}}}))
+	assert.Equal(t, []string{"mount b", "render b"}, componentLog)
+	assert.Contains(t, s.last().(*rend.Root).Components, int64(3))
+}
+
+type tagsLive struct {
+	tags  map[string]any
+	value bool
+}
+
+func (l *tagsLive) Event(s lv.Socket, event string, p params.Params) error {
+	if event == "tag" {
+		l.tags[p.String("value")] = true
+	}
+	return nil
+}
+
+func (l *tagsLive) Render(_ rend.Node) (rend.Node, error) {
+	c := tagsComponent{Tags: tagsProps{Values: l.tags}}
+	if l.value {
+		return html.Div(lv.LiveComponent("tags", c)), nil
+	}
+	return html.Div(lv.LiveComponent("tags", &c)), nil
+}
+
+// tagsProps is comparable, but holds a map behind an interface.
+type tagsProps struct {
+	Values any
+}
+
+type tagsComponent struct {
+	Tags tagsProps
+}
+
+func (c *tagsComponent) Update(s lv.Socket, next any) error {
+	c.Tags = next.(*tagsComponent).Tags
+	return nil
+}
+
+func (c tagsComponent) Render(_ rend.Node) (rend.Node, error) {
+	componentLog = append(componentLog, "render tags")
+	values := c.Tags.Values.(map[string]any)
+	return html.Span(
+		std.Textf("%d", len(values)),
+	), nil
+}
+
+func TestLiveComponentProps(t *testing.T) {
+	tt := []struct {
+		name  string
+		value bool
+	}{
+		{name: "pointer"},
+		{name: "value", value: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+			rt.Handle("/", &tagsLive{tags: map[string]any{}, value: tc.value})
+
+			lc := lv.NewLifecycle(rt, nil, nil)
+			ch := New(lc, mailbox.New(ctx))()
+			s := &testSocket{ch: ch}
+
+			componentLog = nil
+			assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/"}))
+			assert.Equal(t, []string{"render tags"}, componentLog)
+
+			// unchanged props holding a map don't re-render, or panic comparing.
+			componentLog = nil
+			assert.NotPanics(t, func() {
+				assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "noop"}))
+			})
+			assert.Nil(t, componentLog)
+
+			// changes made inside the parent's map are noticed.
+			componentLog = nil
+			assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "tag", "value": "red"}))
+			assert.Equal(t, []string{"render tags"}, componentLog)
+			assert.Contains(t, rend.RenderJSONTree(s.last().(*rend.Root)), "<span>1</span>")
+		})
+	}
+}
+
+// stripFingerprints blanks fingerprints so expectations stay readable.
+func stripFingerprints(t *testing.T, root *rend.Root) string {
+	t.Helper()
+
+	var v any
+	assert.NoError(t, json.Unmarshal([]byte(rend.RenderJSONTree(root)), &v))
+
+	var walk func(any)
+	walk = func(v any) {
+		if m, ok := v.(map[string]any); ok {
+			if _, ok := m["f"]; ok {
+				m["f"] = ""
+			}
+			for _, c := range m {
+				walk(c)
+			}
+		}
+	}
+	walk(v)
+
+	b, err := json.Marshal(v)
+	assert.NoError(t, err)
+
+	return string(b)
+}
+
+type rejectTokenizer struct {
+	err error
+}
+
+func (t *rejectTokenizer) Encode(string, any) (string, error) { return "", nil }
+func (t *rejectTokenizer) Decode(string, string, any) error   { return t.err }
+
+func TestJoinRejectsBadTokens(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tt := []struct {
+		name   string
+		err    error
+		reason string
+	}{
+		{name: "tampered", err: errors.New("bad signature"), reason: "unauthorized"},
+		{name: "expired", err: lv.ErrExpiredToken, reason: "stale"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+			rt.Handle("/", &counterLive{})
+
+			lc := lv.NewLifecycle(rt, &rejectTokenizer{err: tc.err}, nil)
+			ch := New(lc, mailbox.New(ctx))()
+			s := &testSocket{ch: ch}
+
+			err := ch.Join(s, map[string]any{"url": "http://localhost/", "session": "token"})
+			assert.EqualError(t, err, tc.reason)
+			assert.Equal(t, 0, s.count(""))
+		})
+	}
+}
+
+type mountLive struct {
+	user string
+}
+
+func (l *mountLive) Mount(s lv.Socket, p params.Params) error {
+	l.user = p.String("current_user")
+	return nil
+}
+
+func (l *mountLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(std.Text(l.user)), nil
+}
+
+func TestOnMount(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var sessions []map[string]any
+	requireUser := func(s lv.Socket, p params.Params, session map[string]any) error {
+		sessions = append(sessions, session)
+
+		switch session["user"] {
+		case nil:
+			return lv.Halt("/login")
+		case "banned":
+			return errors.New("banned")
+		}
+
+		p.Set("current_user", session["user"])
+		return nil
+	}
+
+	tokens := &jsonTokenizer{}
+
+	tt := []struct {
+		name    string
+		session map[string]any
+		err     string
+		reply   map[string]any
+		user    string
+	}{
+		{name: "continue", session: map[string]any{"user": "bob"}, user: "bob"},
+		{
+			name:    "halt with redirect",
+			session: map[string]any{},
+			reply:   map[string]any{"redirect": map[string]any{"to": "/login"}},
+		},
+		{name: "halt with error", session: map[string]any{"user": "banned"}, err: "banned"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sessions = nil
+
+			view := &mountLive{}
+			rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+			rt.Group("/", view, router.WithSession("admin", requireUser))
+
+			lc := lv.NewLifecycle(rt, tokens, nil)
+			ch := New(lc, mailbox.New(ctx))()
+			s := &testSocket{ch: ch}
+
+			token := pageToken(t, tokens, "/", tc.session)
+
+			err := ch.Join(s, map[string]any{"url": "http://localhost/", "session": token})
+
+			// the client only follows a redirect when the join is refused.
+			var reply *channel.ReplyError
+			switch {
+			case tc.err != "":
+				assert.EqualError(t, err, tc.err)
+			case tc.reply != nil:
+				if assert.ErrorAs(t, err, &reply) {
+					assert.Equal(t, tc.reply, reply.Response)
+				}
+			default:
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, []map[string]any{tc.session}, sessions)
+			assert.Zero(t, s.count("redirect"))
+			assert.Equal(t, tc.user, view.user)
+		})
+	}
+}
+
+type navigateOnMountLive struct{}
+
+func (l *navigateOnMountLive) Mount(s lv.Socket, p params.Params) error {
+	return s.PushNavigate("/other")
+}
+
+func (l *navigateOnMountLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(), nil
+}
+
+func TestRedirectOnJoin(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+	rt.Handle("/", &navigateOnMountLive{})
+
+	tokens := &jsonTokenizer{}
+	ch := New(lv.NewLifecycle(rt, tokens, nil), mailbox.New(ctx))()
+	s := &testSocket{ch: ch}
+
+	err := ch.Join(s, map[string]any{
+		"url":     "http://localhost/",
+		"session": pageToken(t, tokens, "/", nil),
+	})
+
+	var reply *channel.ReplyError
+	if assert.ErrorAs(t, err, &reply) {
+		assert.Equal(t, map[string]any{
+			"live_redirect": map[string]any{"to": "/other", "kind": "push"},
+		}, reply.Response)
+	}
+	assert.Zero(t, s.count("live_redirect"))
+}
+
+func TestJoinBoundToPage(t *testing.T) {
+	tt := []struct {
+		name     string
+		rendered string
+		url      string
+		redirect bool
+	}{
+		{name: "same route", rendered: "/", url: "http://localhost/"},
+		{name: "same live session", rendered: "/", url: "http://localhost/other"},
+		{name: "other live session", rendered: "/", url: "http://localhost/admin", redirect: true},
+		{name: "unknown route", rendered: "/missing", url: "http://localhost/", redirect: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// the admin routes' middleware only runs for their pages.
+			rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+			rt.Handle("/", &mountLive{})
+			rt.Handle("/other", &mountLive{})
+			rt.Handle("/admin", &mountLive{}, router.WithSession("admin"))
+
+			tokens := &jsonTokenizer{}
+			ch := New(lv.NewLifecycle(rt, tokens, nil), mailbox.New(ctx))()
+			s := &testSocket{ch: ch}
+
+			err := ch.Join(s, map[string]any{
+				"url":     tc.url,
+				"session": pageToken(t, tokens, tc.rendered, nil),
+			})
+
+			if !tc.redirect {
+				assert.NoError(t, err)
+				return
+			}
+
+			// the client loads the page, running the route's middleware.
+			var reply *channel.ReplyError
+			if assert.ErrorAs(t, err, &reply) {
+				assert.Equal(t, map[string]any{
+					"redirect": map[string]any{"to": tc.url},
+				}, reply.Response)
+			}
+		})
+	}
+
+	t.Run("missing session", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+		rt.Handle("/", &mountLive{})
+
+		ch := New(lv.NewLifecycle(rt, &jsonTokenizer{}, nil), mailbox.New(ctx))()
+
+		err := ch.Join(&testSocket{ch: ch}, map[string]any{"url": "http://localhost/"})
+		assert.ErrorIs(t, err, lv.ErrInvalidToken)
+	})
+}
+
+// pageToken is the session token the page rendered for path would carry.
+func pageToken(t *testing.T, tok interface {
+	Encode(string, any) (string, error)
+}, path string, session map[string]any) string {
+	token, err := tok.Encode("live_view session", map[string]any{"session": session, "path": path})
+	assert.NoError(t, err)
+	return token
+}
+
+type jsonTokenizer struct{}
+
+func (t *jsonTokenizer) Encode(_ string, v any) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+func (t *jsonTokenizer) Decode(_, s string, v any) error {
+	return json.Unmarshal([]byte(s), v)
+}
+
+type formLive struct {
+	value params.Params
+}
+
+func (l *formLive) Event(s lv.Socket, event string, p params.Params) error {
+	l.value = p.Map("value")
+	return nil
+}
+
+func (l *formLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(), nil
+}
+
+func TestFormEvent(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+	view := &formLive{}
+	rt.Handle("/", view)
+
+	lc := lv.NewLifecycle(rt, nil, nil)
+	ch := New(lc, mailbox.New(ctx))()
+	s := &testSocket{ch: ch}
+
+	assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/"}))
+
+	assert.NoError(t, ch.Message(s, "event", map[string]any{
+		"type":  "form",
+		"event": "validate",
+		"value": "user%5Bname%5D=bob&user%5Btags%5D%5B%5D=a&_target=user%5Bname%5D",
+		"meta":  map[string]any{"step": "1"},
+	}))
+
+	assert.Eventually(t, func() bool {
+		return s.count("diff") == 1
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, "bob", view.value.Map("user").String("name"))
+	assert.Equal(t, []string{"a"}, view.value.Map("user").StringSlice("tags"))
+	assert.Equal(t, []string{"user", "name"}, view.value.Target())
+	assert.Equal(t, "1", view.value.String("step"))
+}
+
+type recoverLive struct {
+	form      *form.Form
+	recovered bool
+}
+
+func (l *recoverLive) Mount(s lv.Socket, p params.Params) error {
+	l.form = form.New("user", nil, form.Validator{"name": {form.Length(3, 0)}})
+	return nil
+}
+
+func (l *recoverLive) Event(s lv.Socket, event string, p params.Params) error {
+	switch event {
+	case "validate":
+		l.form.Change(p.Map("value"))
+	case "recover":
+		l.recovered = true
+		l.form.Change(p.Map("value"))
+	}
+	return nil
+}
+
+func (l *recoverLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.Form(
+		html.Attr("phx-change", "validate"),
+		l.form.Input("name"),
+		l.form.Input("email"),
+	), nil
+}
+
+func TestFormRecovery(t *testing.T) {
+	tt := []struct {
+		name  string
+		event string
+	}{
+		{name: "change event", event: "validate"},
+		{name: "auto recover event", event: "recover"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// each connection sets up its own routes, like handler.handle.
+			connect := func() (*recoverLive, channel.Channel, *testSocket) {
+				view := &recoverLive{}
+				rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+				rt.Handle("/", view)
+
+				ch := New(lv.NewLifecycle(rt, nil, nil), mailbox.New(ctx))()
+				return view, ch, &testSocket{ch: ch}
+			}
+
+			view, ch, s := connect()
+			assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/"}))
+			assert.NoError(t, ch.Message(s, "event", map[string]any{
+				"type":  "form",
+				"event": "validate",
+				"value": "user%5Bname%5D=al&user%5Bemail%5D=&user%5B_unused_email%5D=",
+				"meta":  map[string]any{"_target": "user[name]"},
+			}))
+			assert.Equal(t, "al", view.form.Value("name"))
+
+			// the socket drops and the client rejoins on a new connection.
+			assert.NoError(t, ch.Leave(s))
+
+			view, ch, s = connect()
+			assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/", "_mounts": 1}))
+			assert.Nil(t, view.form.Value("name"))
+
+			// the client pushes each form's state before applying the join.
+			assert.NoError(t, ch.Message(s, "event", map[string]any{
+				"type":  "form",
+				"event": tc.event,
+				"value": "user%5Bname%5D=al&user%5Bemail%5D=&user%5B_unused_email%5D=",
+				"meta":  map[string]any{"_target": "user[name]"},
+			}))
+
+			assert.Equal(t, tc.event == "recover", view.recovered)
+			assert.Equal(t, "al", view.form.Value("name"))
+			assert.Equal(t, []string{"should be at least 3 character(s)"}, view.form.Errors("name"))
+			assert.Nil(t, view.form.Errors("email"))
+
+			diff := rend.RenderJSONTree(s.last().(*rend.Root))
+			assert.Contains(t, diff, `"al"`)
+			assert.Contains(t, diff, "should be at least 3 character(s)")
+		})
+	}
+}
+
+// signingTokenizer signs tokens with an HMAC, like the handler's.
+type signingTokenizer struct{}
+
+func (t *signingTokenizer) sign(salt, data string) string {
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(salt))
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (t *signingTokenizer) Encode(salt string, v any) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	data := base64.RawURLEncoding.EncodeToString(b)
+	return data + "." + t.sign(salt, data), nil
+}
+
+func (t *signingTokenizer) Decode(salt, s string, v any) error {
+	data, sig, _ := strings.Cut(s, ".")
+	if !hmac.Equal([]byte(sig), []byte(t.sign(salt, data))) {
+		return lv.ErrInvalidToken
+	}
+
+	b, err := base64.RawURLEncoding.DecodeString(data)
+	if err != nil {
+		return lv.ErrInvalidToken
+	}
+
+	return json.Unmarshal(b, v)
+}
+
+type flashLive struct {
+	flash lv.Flash
+}
+
+func (l *flashLive) Mount(s lv.Socket, p params.Params) error {
+	l.flash = s.Flash()
+	return nil
+}
+
+func (l *flashLive) Event(s lv.Socket, event string, p params.Params) error {
+	switch event {
+	case "put":
+		s.PutFlash("error", "Failed")
+	case "patch":
+		return s.PushPatch("/", lv.WithFlash("info", "Patched"))
+	case "navigate":
+		return s.PushNavigate("/other", lv.WithFlash("info", "Moved"))
+	}
+	return nil
+}
+
+func (l *flashLive) Render(_ rend.Node) (rend.Node, error) {
+	return components.FlashGroup(), nil
+}
+
+func TestFlash(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+	view := &flashLive{}
+	rt.Handle("/", view)
+
+	tokenizer := &signingTokenizer{}
+	mb := mailbox.New(ctx)
+	ch := New(lv.NewLifecycle(rt, tokenizer, nil), mb)()
+	s := &testSocket{ch: ch}
+
+	// the view's flash is written in the mailbox, so it's read there too.
+	flash := func() (f lv.Flash) {
+		mb.Call(func() error {
+			f = maps.Clone(view.flash)
+			return nil
+		})
+		return f
+	}
+
+	// after a live redirect the flash comes back in the join params.
+	signed, err := tokenizer.Encode("live_view flash", map[string]any{"info": "Welcome"})
+	assert.NoError(t, err)
+
+	assert.NoError(t, ch.Join(s, map[string]any{
+		"url":     "http://localhost/",
+		"session": pageToken(t, tokenizer, "/", nil),
+		"flash":   signed,
+	}))
+	assert.Equal(t, lv.Flash{"info": "Welcome"}, flash())
+	assert.Contains(t, rend.RenderJSONTree(s.last().(map[string]any)["rendered"].(*rend.Root)), "Welcome")
+
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "put"}))
+	assert.Equal(t, lv.Flash{"info": "Welcome", "error": "Failed"}, flash())
+	assert.Contains(t, rend.RenderJSONTree(s.last().(*rend.Root)), "Failed")
+
+	assert.NoError(t, ch.Message(s, "event", map[string]any{
+		"event": "lv:clear-flash",
+		"value": map[string]any{"key": "info"},
+	}))
+	assert.Equal(t, lv.Flash{"error": "Failed"}, flash())
+
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "patch"}))
+	assert.Eventually(t, func() bool {
+		return s.count("diff") == 4
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, lv.Flash{"error": "Failed", "info": "Patched"}, flash())
+
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "lv:clear-flash"}))
+	assert.Empty(t, flash())
+
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "put"}))
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "navigate"}))
+
+	var redirect map[string]any
+	for i, event := range s.pushes {
+		if event == "live_redirect" {
+			redirect = s.payloads[i].(map[string]any)
+		}
+	}
+
+	carried := map[string]any{}
+	assert.NoError(t, tokenizer.Decode("live_view flash", redirect["flash"].(string), &carried))
+	assert.Equal(t, map[string]any{"error": "Failed", "info": "Moved"}, carried)
+}
+
+func TestPutFlashDetached(t *testing.T) {
+	// sockets not yet attached to a liveview keep their flash.
+	s := lv.NewSocket(&testSocket{})
+	s.PutFlash("info", "Saved")
+
+	assert.Equal(t, lv.Flash{"info": "Saved"}, s.Flash())
+}
+
+func TestForgedFlash(t *testing.T) {
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"info":"Forged"}`))
+
+	// signed, but for the page's session rather than the flash.
+	session, err := (&signingTokenizer{}).Encode("live_view session", map[string]any{"info": "Forged"})
+	assert.NoError(t, err)
+
+	tt := []struct {
+		name  string
+		flash any
+	}{
+		{name: "unsigned", flash: base64.StdEncoding.EncodeToString([]byte(`{"info":"Forged"}`))},
+		{name: "bad signature", flash: forged + ".bad"},
+		{name: "map", flash: map[string]any{"info": "Forged"}},
+		{name: "session token", flash: session},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+			view := &flashLive{}
+			rt.Handle("/", view)
+
+			tokenizer := &signingTokenizer{}
+			ch := New(lv.NewLifecycle(rt, tokenizer, nil), mailbox.New(ctx))()
+			s := &testSocket{ch: ch}
+
+			assert.NoError(t, ch.Join(s, map[string]any{
+				"url":     "http://localhost/",
+				"session": pageToken(t, tokenizer, "/", nil),
+				"flash":   tc.flash,
+			}))
+			assert.Empty(t, view.flash)
+			assert.NotContains(t, rend.RenderJSONTree(s.last().(map[string]any)["rendered"].(*rend.Root)), "Forged")
+		})
+	}
+}
+
+type hookLive struct {
+	pings int
+}
+
+func (l *hookLive) EventReply(s lv.Socket, event string, p params.Params) (any, error) {
+	var ping struct {
+		Sent int64 `param:"sent"`
+	}
+	err := params.Bind(p.Map("value"), &ping)
+	if err != nil {
+		return nil, err
+	}
+
+	l.pings++
+
+	err = s.PushEvents(
+		lv.ClientEvent{Event: "log", Payload: map[string]any{"msg": "ping"}},
+		lv.ClientEvent{Event: "highlight", Payload: map[string]any{"id": "pings"}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]any{"count": l.pings, "sent": ping.Sent}, nil
+}
+
+func (l *hookLive) Info(s lv.Socket, msg any) error {
+	return s.PushEvent("tick", nil)
+}
+
+func (l *hookLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.P(std.Text(&l.pings)), nil
+}
+
+func TestHookEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+	view := &hookLive{}
+	rt.Handle("/", view)
+
+	lc := lv.NewLifecycle(rt, nil, nil)
+	ch := New(lc, mailbox.New(ctx))()
+	s := &testSocket{ch: ch}
+
+	assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/"}))
+
+	// hooks push events with type "hook" and the payload as the value.
+	assert.NoError(t, ch.Message(s, "event", map[string]any{
+		"type":  "hook",
+		"event": "ping",
+		"value": map[string]any{"sent": float64(42)},
+	}))
+
+	// the reply and the pushed events are sent with the diff.
+	assert.JSONEq(t, `{
+		"0": "1",
+		"e": [["log", {"msg": "ping"}], ["highlight", {"id": "pings"}]],
+		"r": {"count": 1, "sent": 42}
+	}`, rend.RenderJSONTree(s.last().(*rend.Root)))
+
+	// events are sent even when nothing else changed.
+	assert.NoError(t, s.PushSelf("info", lv.Info{Event: "tick"}))
+	assert.Eventually(t, func() bool {
+		return s.count("diff") == 2
+	}, time.Second, time.Millisecond)
+
+	assert.JSONEq(t, `{"e": [["tick", null]]}`, rend.RenderJSONTree(s.last().(*rend.Root)))
+}
+
+type storedSocketLive struct {
+	socket lv.Socket
+}
+
+func (l *storedSocketLive) Mount(s lv.Socket, p params.Params) error {
+	l.socket = s
+	return nil
+}
+
+func (l *storedSocketLive) Event(s lv.Socket, event string, p params.Params) error {
+	err := s.PushEvent("bye", nil)
+	if err != nil {
+		return err
+	}
+
+	return s.PushNavigate("/other")
+}
+
+func (l *storedSocketLive) Render(_ rend.Node) (rend.Node, error) {
+	return html.Div(), nil
+}
+
+func TestPushEventOutsideRender(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rt := router.NewRouter(func(n ...rend.Node) rend.Node { return std.Group(n...) })
+	view := &storedSocketLive{}
+	rt.Handle("/", view)
+
+	ch := New(lv.NewLifecycle(rt, nil, nil), mailbox.New(ctx))()
+	s := &testSocket{ch: ch}
+
+	assert.NoError(t, ch.Join(s, map[string]any{"url": "http://localhost/"}))
+
+	// pushed from a socket kept past its callback, like in a goroutine.
+	assert.NoError(t, view.socket.PushEvent("later", map[string]any{"n": 1}))
+	assert.Eventually(t, func() bool {
+		return s.count("diff") == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, map[string]any{"e": [][]any{{"later", map[string]any{"n": 1}}}}, s.last())
+
+	// pushed before a redirect, so there's no render to send them with.
+	assert.NoError(t, ch.Message(s, "event", map[string]any{"event": "leave"}))
+	assert.Eventually(t, func() bool {
+		return s.count("diff") == 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, map[string]any{"e": [][]any{{"bye", nil}}}, s.last())
+}
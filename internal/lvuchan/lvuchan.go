@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/sethpollack/go-live-view/channel"
+	"github.com/sethpollack/go-live-view/internal/mailbox"
 	"github.com/sethpollack/go-live-view/params"
 )
 
@@ -16,14 +17,16 @@ type lvuLifecycle interface {
 
 type lvuChannel struct {
 	lc        lvuLifecycle
+	mb        *mailbox.Mailbox
 	configRef string
 	ref       string
 }
 
-func New(lc lvuLifecycle) func() channel.Channel {
+func New(lc lvuLifecycle, mb *mailbox.Mailbox) func() channel.Channel {
 	return func() channel.Channel {
 		return &lvuChannel{
 			lc: lc,
+			mb: mb,
 		}
 	}
 }
@@ -55,7 +58,9 @@ func (l *lvuChannel) Message(s channel.Socket, event string, p any) error {
 			return fmt.Errorf("invalid chunk data")
 		}
 
-		err := l.lc.Chunk(l.configRef, l.ref, data, s.Close)
+		err := l.mb.Call(func() error {
+			return l.lc.Chunk(l.configRef, l.ref, data, s.Close)
+		})
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,100 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var ErrClosed = errors.New("mailbox closed")
+
+// Mailbox runs queued functions one at a time on a single goroutine, so
+// state owned by the mailbox is never touched concurrently.
+type Mailbox struct {
+	mu     sync.Mutex
+	queue  []func()
+	notify chan struct{}
+	done   <-chan struct{}
+}
+
+func New(ctx context.Context) *Mailbox {
+	m := &Mailbox{
+		notify: make(chan struct{}, 1),
+		done:   ctx.Done(),
+	}
+
+	go m.run()
+
+	return m
+}
+
+// Call runs fn on the mailbox goroutine and waits for its result. It must
+// not be called from inside the mailbox.
+func (m *Mailbox) Call(fn func() error) error {
+	result := make(chan error, 1)
+
+	if !m.Cast(func() { result <- fn() }) {
+		return ErrClosed
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-m.done:
+		return ErrClosed
+	}
+}
+
+// Cast queues fn without waiting. It is safe to call from inside the
+// mailbox. It returns false if the mailbox has stopped.
+func (m *Mailbox) Cast(fn func()) bool {
+	select {
+	case <-m.done:
+		return false
+	default:
+	}
+
+	m.mu.Lock()
+	m.queue = append(m.queue, fn)
+	m.mu.Unlock()
+
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+
+	return true
+}
+
+func (m *Mailbox) run() {
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-m.notify:
+		}
+
+		for {
+			fn, ok := m.next()
+			if !ok {
+				break
+			}
+			fn()
+		}
+	}
+}
+
+func (m *Mailbox) next() (func(), bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.queue) == 0 {
+		return nil, false
+	}
+
+	fn := m.queue[0]
+	m.queue[0] = nil
+	m.queue = m.queue[1:]
+
+	return fn, true
+}
@@ -0,0 +1,88 @@
+package mailbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailboxSerializes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mb := New(ctx)
+
+	// counter is deliberately unsynchronized; the race detector fails the
+	// test if the mailbox ever runs two functions at once.
+	counter := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			mb.Call(func() error {
+				counter++
+				return nil
+			})
+		}()
+		go func() {
+			defer wg.Done()
+			done := make(chan struct{})
+			mb.Cast(func() {
+				counter++
+				close(done)
+			})
+			<-done
+		}()
+	}
+	wg.Wait()
+
+	mb.Call(func() error {
+		assert.Equal(t, 200, counter)
+		return nil
+	})
+}
+
+func TestMailboxCastFromInside(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	mb := New(ctx)
+
+	order := []string{}
+	done := make(chan struct{})
+
+	err := mb.Call(func() error {
+		mb.Cast(func() {
+			order = append(order, "cast")
+			close(done)
+		})
+		order = append(order, "call")
+		return nil
+	})
+	assert.NoError(t, err)
+
+	<-done
+	assert.Equal(t, []string{"call", "cast"}, order)
+}
+
+func TestMailboxCallError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	mb := New(ctx)
+
+	expected := errors.New("failed")
+	assert.Equal(t, expected, mb.Call(func() error { return expected }))
+
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		return mb.Call(func() error { return nil }) == ErrClosed
+	}, time.Second, time.Millisecond)
+	assert.False(t, mb.Cast(func() {}))
+}
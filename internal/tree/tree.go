@@ -1,22 +1,41 @@
 package tree
 
 import (
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 )
 
+// Node is a segment of a route pattern. Patterns are made of "/" separated
+// segments, each one of:
+//
+//	static        matches itself
+//	:name<type>   matches a value of type: int, float, bool, uuid or slug
+//	:name~rREGEX  matches values the regular expression fully matches
+//	:name         matches any non empty value
+//	*name         matches the rest of the path, and must come last. A bare
+//	              * captures it into "*".
+//
+// When more than one pattern matches a path, earlier segments decide: static
+// beats constrained (typed or regex), which beats a plain param, which beats a
+// catch-all. Constrained segments are tried in the order they were added.
 type Node[T any] struct {
-	segment  string
-	children map[string]*Node[T]
+	seg     Segment
+	pattern string
+
+	static  map[string]*Node[T]
+	dynamic []*Node[T]
+
 	route    T
+	hasRoute bool
 	depth    int
 }
 
 func New[T any]() *Node[T] {
 	return &Node[T]{
-		segment:  "",
-		children: make(map[string]*Node[T]),
-		depth:    1,
+		static: make(map[string]*Node[T]),
+		depth:  1,
 	}
 }
 
@@ -24,107 +43,281 @@ func (n *Node[T]) GetRoute() T {
 	return n.route
 }
 
+// AddRoute adds route under path, failing when the path is already taken or
+// is ambiguous with an existing pattern.
 func (n *Node[T]) AddRoute(path string, route T) error {
 	segments := strings.Split(path, "/")[1:]
 
-	inserted := false
-
 	current := n
-	for _, segment := range segments {
-		if child, ok := current.children[segment]; ok {
-			current = child
-			continue
+	for i, s := range segments {
+		seg, err := ParseSegment(s)
+		if err != nil {
+			return fmt.Errorf("route %s: %w", path, err)
 		}
 
-		newNode := &Node[T]{
-			segment:  segment,
-			children: make(map[string]*Node[T]),
-			depth:    current.depth + 1,
+		if seg.Kind == CatchAll && i != len(segments)-1 {
+			return fmt.Errorf("route %s: catch-all %s must be the last segment", path, s)
 		}
 
-		current.children[segment] = newNode
-		current = newNode
-		inserted = true
+		current, err = current.child(seg)
+		if err != nil {
+			return fmt.Errorf("route %s %w", path, err)
+		}
 	}
 
-	if !inserted {
+	if current.hasRoute {
 		return fmt.Errorf("route %s already exists", path)
 	}
 
 	current.route = route
+	current.hasRoute = true
 
 	return nil
 }
 
+// child returns the node for seg, adding it when needed.
+func (n *Node[T]) child(seg Segment) (*Node[T], error) {
+	if seg.Kind == Static {
+		if c, ok := n.static[seg.raw]; ok {
+			return c, nil
+		}
+
+		c := n.newChild(seg)
+		n.static[seg.raw] = c
+
+		return c, nil
+	}
+
+	for _, c := range n.dynamic {
+		if c.seg.raw == seg.raw {
+			return c, nil
+		}
+
+		// the same match under another name can never be reached.
+		if c.seg.Kind == seg.Kind && c.seg.constraint == seg.constraint {
+			return nil, fmt.Errorf("conflicts with %s", c.pattern)
+		}
+	}
+
+	c := n.newChild(seg)
+
+	// keep dynamic children in precedence order, then insertion order.
+	i := len(n.dynamic)
+	for i > 0 && n.dynamic[i-1].seg.Kind > seg.Kind {
+		i--
+	}
+	n.dynamic = append(n.dynamic, nil)
+	copy(n.dynamic[i+1:], n.dynamic[i:])
+	n.dynamic[i] = c
+
+	return c, nil
+}
+
+func (n *Node[T]) newChild(seg Segment) *Node[T] {
+	return &Node[T]{
+		seg:     seg,
+		pattern: n.pattern + "/" + seg.raw,
+		static:  make(map[string]*Node[T]),
+		depth:   n.depth + 1,
+	}
+}
+
+// ErrNotFound is returned by FindNode when no route matches the whole
+// path.
+var ErrNotFound = errors.New("route not found")
+
+// FindNode returns the node for path along with its params. Only a route
+// matching the whole path is found, so /users/1/edit never finds /users/:id.
 func (n *Node[T]) FindNode(path string) (*Node[T], map[string]any, error) {
+	node, params, exact := n.Lookup(path)
+	if !exact {
+		return nil, nil, ErrNotFound
+	}
+
+	return node, params, nil
+}
+
+// Lookup returns the node for path along with its params, reporting
+// whether a route matched the whole path. When none did, the deepest node
+// reached is returned.
+func (n *Node[T]) Lookup(path string) (*Node[T], map[string]any, bool) {
 	segments := strings.Split(path, "/")[1:]
 
-	currentFind := n
-	currentParams := make(map[string]any)
+	m := n.find(segments, map[string]any{})
 
-	current := n
-	for i, segment := range segments {
-		currentPath := strings.Join(segments[i:], "/")
+	return m.node, m.params, m.exact
+}
 
-		if child, ok := current.children[segment]; ok {
-			found, params, err := child.FindNode(currentPath)
-			if err != nil {
-				return nil, nil, err
+type match[T any] struct {
+	node   *Node[T]
+	params map[string]any
+	exact  bool
+}
+
+func (n *Node[T]) find(segments []string, params map[string]any) match[T] {
+	if len(segments) == 0 {
+		if n.hasRoute {
+			return match[T]{node: n, params: params, exact: true}
+		}
+
+		// catch-alls also match nothing.
+		for _, c := range n.dynamic {
+			if c.seg.Kind == CatchAll && c.hasRoute {
+				return match[T]{node: c, params: with(params, c.seg.Key, ""), exact: true}
 			}
+		}
+
+		return match[T]{node: n, params: params}
+	}
 
-			if found.depth > currentFind.depth {
-				currentParams = params
-				currentFind = found
+	best := match[T]{node: n, params: params}
+	try := func(m match[T]) bool {
+		if m.exact {
+			best = m
+			return true
+		}
+		if m.node.depth > best.node.depth {
+			best = m
+		}
+		return false
+	}
+
+	segment := segments[0]
+
+	if c, ok := n.static[segment]; ok {
+		if try(c.find(segments[1:], params)) {
+			return best
+		}
+	}
+
+	for _, c := range n.dynamic {
+		if c.seg.Kind == CatchAll {
+			if c.hasRoute {
+				return match[T]{node: c, params: with(params, c.seg.Key, strings.Join(segments, "/")), exact: true}
 			}
+			continue
+		}
+
+		if !c.seg.Match(segment) {
+			continue
+		}
+
+		if try(c.find(segments[1:], with(params, c.seg.Key, segment))) {
+			return best
+		}
+	}
+
+	return best
+}
+
+// with returns a copy of params with key set, so sibling branches don't
+// see each other's params.
+func with(params map[string]any, key string, value string) map[string]any {
+	p := make(map[string]any, len(params)+1)
+	for k, v := range params {
+		p[k] = v
+	}
+	p[key] = value
+	return p
+}
+
+// Kind is a type of segment, in order of precedence.
+type Kind int
+
+const (
+	Static Kind = iota
+	Constrained
+	Param
+	CatchAll
+)
+
+// Segment is a parsed pattern segment.
+type Segment struct {
+	Kind Kind
+	// Key is the param the segment is captured into.
+	Key string
+	// Type is the type of a typed param.
+	Type string
+
+	raw        string
+	constraint string
+	re         *regexp.Regexp
+}
+
+var types = map[string]*regexp.Regexp{
+	"int":   regexp.MustCompile(`^-?[0-9]+$`),
+	"float": regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`),
+	"bool":  regexp.MustCompile(`^(true|false)$`),
+	"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`),
+	"slug":  regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`),
+}
+
+// ParseSegment parses a single segment of a route pattern.
+func ParseSegment(s string) (Segment, error) {
+	seg := Segment{raw: s}
+
+	switch {
+	case strings.HasPrefix(s, "*"):
+		seg.Kind = CatchAll
+		seg.Key = s[1:]
+		if seg.Key == "" {
+			seg.Key = "*"
 		}
 
-		for key, child := range current.children {
-			if strings.HasPrefix(key, ":") {
-				found, params, err := child.FindNode(currentPath)
-				if err != nil {
-					return nil, nil, err
-				}
+	case strings.HasPrefix(s, ":"):
+		seg.Kind = Param
+		seg.Key = s[1:]
 
-				if found.depth > currentFind.depth {
-					params[key[1:]] = segment
-					currentParams = params
-					currentFind = found
-				}
+		if i := strings.Index(seg.Key, "~r"); i >= 0 {
+			re, err := regexp.Compile("^(?:" + seg.Key[i+2:] + ")$")
+			if err != nil {
+				return seg, fmt.Errorf("invalid regex in %s: %w", s, err)
 			}
 
-			if key == "*" {
-				found, params, err := child.FindNode(currentPath)
-				if err != nil {
-					return nil, nil, err
-				}
-
-				if found.depth > currentFind.depth {
-					params["*"] = segment
-					currentParams = params
-					currentFind = found
-				}
+			seg.Kind = Constrained
+			seg.constraint = re.String()
+			seg.re = re
+			seg.Key = seg.Key[:i]
+		} else if i := strings.Index(seg.Key, "<"); i >= 0 && strings.HasSuffix(seg.Key, ">") {
+			typ := seg.Key[i+1 : len(seg.Key)-1]
+
+			re, ok := types[typ]
+			if !ok {
+				return seg, fmt.Errorf("unknown type %s in %s", typ, s)
 			}
-			// if strings.HasPrefix(key, "~r") {
-			// 	regex := key[2:]
-			// 	r, err := regexp.Compile(regex)
-			// 	if err != nil {
-			// 		return nil, nil, err
-			// 	}
-			// 	if r.MatchString(segment) {
-			// 		found, params, err := child.FindNode(currentPath)
-			// 		if err != nil {
-			// 			return nil, nil, err
-			// 		}
-
-			// 		if found.depth > currentFind.depth {
-			// 			params[key] = segment
-			// 			currentParams = params
-			// 			currentFind = found
-			// 		}
-			// 	}
-			// }
+
+			seg.Kind = Constrained
+			seg.Type = typ
+			seg.constraint = re.String()
+			seg.re = re
+			seg.Key = seg.Key[:i]
 		}
+
+		if seg.Key == "" {
+			return seg, fmt.Errorf("missing param name in %s", s)
+		}
+
+	default:
+		seg.Kind = Static
 	}
 
-	return currentFind, currentParams, nil
+	return seg, nil
+}
+
+// Pattern returns the regexp a constrained segment must match, or "" for
+// other segments.
+func (s Segment) Pattern() string {
+	return s.constraint
+}
+
+// Match reports whether a path segment matches s.
+func (s Segment) Match(segment string) bool {
+	switch s.Kind {
+	case Static:
+		return segment == s.raw
+	case Constrained:
+		return s.re.MatchString(segment)
+	default:
+		return segment != ""
+	}
 }
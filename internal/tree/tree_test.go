@@ -24,11 +24,16 @@ func TestFindNode(t *testing.T) {
 			expectParams: map[string]any{},
 		},
 		{
-			name:         "partial match",
-			paths:        []string{"/foo"},
-			search:       "/foo/bar",
-			expect:       "/foo",
-			expectParams: map[string]any{},
+			name:   "partial match",
+			paths:  []string{"/foo"},
+			search: "/foo/bar",
+			err:    ErrNotFound,
+		},
+		{
+			name:   "partial match with param",
+			paths:  []string{"/admin/users/:id"},
+			search: "/admin/users/1/more",
+			err:    ErrNotFound,
 		},
 		{
 			name:         "simple",
@@ -75,6 +80,114 @@ func TestFindNode(t *testing.T) {
 				"*":  "bar",
 			},
 		},
+		{
+			name:         "catch all captures the rest",
+			paths:        []string{"/files/*path"},
+			search:       "/files/a/b/c",
+			expect:       "/files/*path",
+			expectParams: map[string]any{"path": "a/b/c"},
+		},
+		{
+			name:         "catch all matches nothing",
+			paths:        []string{"/files/*path"},
+			search:       "/files",
+			expect:       "/files/*path",
+			expectParams: map[string]any{"path": ""},
+		},
+		{
+			name:         "typed param",
+			paths:        []string{"/posts/:id<int>", "/posts/:slug"},
+			search:       "/posts/12",
+			expect:       "/posts/:id<int>",
+			expectParams: map[string]any{"id": "12"},
+		},
+		{
+			name:         "typed param mismatch",
+			paths:        []string{"/posts/:id<int>", "/posts/:slug"},
+			search:       "/posts/hello",
+			expect:       "/posts/:slug",
+			expectParams: map[string]any{"slug": "hello"},
+		},
+		{
+			name:         "regex param",
+			paths:        []string{"/tags/:tag~r[a-z]+", "/tags/*rest"},
+			search:       "/tags/go",
+			expect:       "/tags/:tag~r[a-z]+",
+			expectParams: map[string]any{"tag": "go"},
+		},
+		{
+			name:         "regex is anchored",
+			paths:        []string{"/tags/:tag~r[a-z]+", "/tags/*rest"},
+			search:       "/tags/go1",
+			expect:       "/tags/*rest",
+			expectParams: map[string]any{"rest": "go1"},
+		},
+		{
+			name:         "precedence",
+			paths:        []string{"/a/*rest", "/a/:id", "/a/:id<uuid>", "/a/new"},
+			search:       "/a/new",
+			expect:       "/a/new",
+			expectParams: map[string]any{},
+		},
+		{
+			name:         "precedence constrained",
+			paths:        []string{"/a/*rest", "/a/:id", "/a/:id<uuid>", "/a/new"},
+			search:       "/a/9b2f4c8e-1d2a-4f5b-8c3d-2e1f0a9b8c7d",
+			expect:       "/a/:id<uuid>",
+			expectParams: map[string]any{"id": "9b2f4c8e-1d2a-4f5b-8c3d-2e1f0a9b8c7d"},
+		},
+		{
+			name:         "precedence param",
+			paths:        []string{"/a/*rest", "/a/:id", "/a/:id<uuid>", "/a/new"},
+			search:       "/a/123",
+			expect:       "/a/:id",
+			expectParams: map[string]any{"id": "123"},
+		},
+		{
+			name:         "precedence catch all",
+			paths:        []string{"/a/*rest", "/a/:id", "/a/:id<uuid>", "/a/new"},
+			search:       "/a/123/456",
+			expect:       "/a/*rest",
+			expectParams: map[string]any{"rest": "123/456"},
+		},
+		{
+			name:         "backtracks to a full match",
+			paths:        []string{"/a/new", "/a/:id/edit"},
+			search:       "/a/new/edit",
+			expect:       "/a/:id/edit",
+			expectParams: map[string]any{"id": "new"},
+		},
+		{
+			name:         "parent added after child",
+			paths:        []string{"/foo/bar", "/foo"},
+			search:       "/foo",
+			expect:       "/foo",
+			expectParams: map[string]any{},
+		},
+		{
+			name:   "ambiguous params",
+			paths:  []string{"/foo/:id", "/foo/:name"},
+			search: "/foo/1",
+			err:    errors.New("route /foo/:name conflicts with /foo/:id"),
+		},
+		{
+			name:   "ambiguous catch alls",
+			paths:  []string{"/foo/*", "/foo/*rest"},
+			search: "/foo/1",
+			err:    errors.New("route /foo/*rest conflicts with /foo/*"),
+		},
+		{
+			name:   "catch all not last",
+			paths:  []string{"/foo/*rest/bar"},
+			search: "/foo/1",
+			err:    errors.New("route /foo/*rest/bar: catch-all *rest must be the last segment"),
+		},
+		{
+			name:   "unknown type",
+			paths:  []string{"/foo/:id<date>"},
+			search: "/foo/1",
+			err:    errors.New("route /foo/:id<date>: unknown type date in :id<date>"),
+		},
 		{
 			name:   "duplicate route",
 			paths:  []string{"/foo", "/foo"},
@@ -89,7 +202,7 @@ func TestFindNode(t *testing.T) {
 			for _, path := range tc.paths {
 				err := tree.AddRoute(path, path)
 				if err != nil {
-					assert.Equal(t, tc.err, err)
+					assert.EqualError(t, err, tc.err.Error())
 					return
 				}
 			}
@@ -97,9 +210,39 @@ func TestFindNode(t *testing.T) {
 			node, params, err := tree.FindNode(tc.search)
 
 			assert.Equal(t, tc.err, err)
+			if err != nil {
+				assert.Nil(t, node)
+				return
+			}
 			assert.Equal(t, tc.expect, node.GetRoute())
 			assert.Equal(t, tc.expectParams, params)
 
 		})
 	}
 }
+
+func TestLookup(t *testing.T) {
+	tree := New[string]()
+	assert.NoError(t, tree.AddRoute("/foo", "/foo"))
+	assert.NoError(t, tree.AddRoute("/files/*path", "/files/*path"))
+
+	tt := []struct {
+		search string
+		expect string
+		exact  bool
+	}{
+		{search: "/foo", expect: "/foo", exact: true},
+		{search: "/foo/bar", expect: "/foo", exact: false},
+		{search: "/files/a/b", expect: "/files/*path", exact: true},
+		{search: "/bar", expect: "", exact: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.search, func(t *testing.T) {
+			node, _, exact := tree.Lookup(tc.search)
+
+			assert.Equal(t, tc.expect, node.GetRoute())
+			assert.Equal(t, tc.exact, exact)
+		})
+	}
+}
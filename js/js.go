@@ -2,7 +2,6 @@ package js
 
 import (
 	"encoding/json"
-	"html"
 	"strings"
 )
 
@@ -17,7 +16,7 @@ func JS(ops ...Operation) string {
 	if err != nil {
 		return ""
 	}
-	return html.EscapeString(string(jsonData))
+	return string(jsonData)
 }
 
 type PushArgs struct {
@@ -0,0 +1,456 @@
+package liveview
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sethpollack/go-live-view/html"
+	"github.com/sethpollack/go-live-view/internal/ref"
+	"github.com/sethpollack/go-live-view/rend"
+)
+
+var _ rend.Registry = (*components)(nil)
+
+// Updater is implemented by live components that want to receive the value
+// their parent renders them with. It's called whenever that value changes,
+// so the component can copy across what it needs while keeping its state.
+type Updater interface {
+	Update(Socket, any) error
+}
+
+func TryUpdate(a any, s Socket, next any) error {
+	if m, ok := a.(Updater); ok {
+		return m.Update(s, next)
+	}
+
+	return nil
+}
+
+type liveComponent struct {
+	id   string
+	view View
+}
+
+// LiveComponent renders a stateful component. The first value rendered
+// under id is mounted and kept, receiving Mount, Update, Event and Unmount
+// callbacks until the client removes it from the page. Events sent with
+// phx-target set to the component (see Myself) are handled by it instead
+// of the parent view, and only the component is re-rendered.
+func LiveComponent(id string, view View) rend.Node {
+	return &liveComponent{
+		id:   id,
+		view: view,
+	}
+}
+
+func (c *liveComponent) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Builder) error {
+	reg, ok := root.Registry().(*components)
+	if !ok || !diff {
+		return c.renderStatic(diff, root, t, b)
+	}
+
+	e, err := reg.mount(root, c.id, c.view)
+	if err != nil {
+		return err
+	}
+
+	err = reg.render(root, e)
+	if err != nil {
+		return err
+	}
+
+	t.AddDynamic(e.cid)
+	t.AddStatic(b.String())
+	b.Reset()
+
+	return nil
+}
+
+// renderStatic is used for the dead render, before there is a socket.
+func (c *liveComponent) renderStatic(diff bool, root *rend.Root, t *rend.Rend, b *strings.Builder) error {
+	err := TryMount(c.view, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	node, err := c.view.Render(nil)
+	if err != nil {
+		return err
+	}
+
+	if node == nil {
+		return nil
+	}
+
+	if diff {
+		t.AddComponent(root, rend.Render(root, node))
+		t.AddStatic(b.String())
+		b.Reset()
+
+		return nil
+	}
+
+	return node.Render(diff, root, t, b)
+}
+
+type myself struct{}
+
+// Myself renders phx-target for the live component being rendered, routing
+// the element's events to it.
+func Myself() rend.Node {
+	return html.Attrs(&myself{})
+}
+
+func (m *myself) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Builder) error {
+	reg, ok := root.Registry().(*components)
+	if !ok || len(reg.stack) == 0 {
+		return nil
+	}
+
+	// cids are stable for the life of a component so they can stay static.
+	_, err := b.Write([]byte(fmt.Sprintf(" phx-target=\"%d\"", reg.stack[len(reg.stack)-1].cid)))
+	return err
+}
+
+type componentEntry struct {
+	id   string
+	cid  int64
+	view View
+	// props is a shallow copy of the value last rendered by the parent.
+	props any
+
+	rend *rend.Rend
+	// owned holds the stateless components rendered inside this one and
+	// the cids of directly nested live components.
+	owned map[int64]*rend.Rend
+
+	dirty      bool
+	destroying bool
+}
+
+// components is the per connection registry of live components.
+type components struct {
+	socket Socket
+	ref    *ref.Ref
+
+	byID   map[string]*componentEntry
+	byCID  map[int64]*componentEntry
+	owners map[int64]int64
+
+	// pinned holds the cids of the last full render while a single
+	// component is re-rendered, so nested components don't reuse them.
+	pinned map[int64]*rend.Rend
+
+	stack []*componentEntry
+}
+
+func newComponents() *components {
+	return &components{
+		ref:    ref.New(0),
+		byID:   map[string]*componentEntry{},
+		byCID:  map[int64]*componentEntry{},
+		owners: map[int64]int64{},
+	}
+}
+
+func (r *components) Reserved(cid int64) bool {
+	if _, ok := r.byCID[cid]; ok {
+		return true
+	}
+
+	if _, ok := r.owners[cid]; ok {
+		return true
+	}
+
+	_, ok := r.pinned[cid]
+	return ok
+}
+
+func (r *components) get(cid int64) *componentEntry {
+	return r.byCID[cid]
+}
+
+func (r *components) mount(root *rend.Root, id string, view View) (*componentEntry, error) {
+	e, ok := r.byID[id]
+	if !ok {
+		e = &componentEntry{
+			id:    id,
+			cid:   r.nextCID(root),
+			view:  view,
+			props: snapshot(view),
+			dirty: true,
+		}
+
+		r.byID[id] = e
+		r.byCID[e.cid] = e
+
+		return e, TryMount(view, r.socket, nil)
+	}
+
+	e.destroying = false
+
+	if sameValue(e.view, view) {
+		// the parent owns the state, so there's no way to tell what changed.
+		e.dirty = true
+		return e, nil
+	}
+
+	props := snapshot(view)
+	if reflect.DeepEqual(e.props, props) {
+		return e, nil
+	}
+
+	e.props = props
+	e.dirty = true
+
+	return e, TryUpdate(e.view, r.socket, view)
+}
+
+// render adds e and everything nested in it to root, only calling the
+// component's Render when it has changed.
+func (r *components) render(root *rend.Root, e *componentEntry) error {
+	if !e.dirty && e.rend != nil {
+		r.restore(root, e)
+		return nil
+	}
+
+	r.release(e)
+
+	before := map[int64]bool{}
+	for cid := range root.Components {
+		before[cid] = true
+	}
+
+	r.stack = append(r.stack, e)
+	defer func() {
+		r.stack = r.stack[:len(r.stack)-1]
+	}()
+
+	node, err := e.view.Render(nil)
+	if err != nil {
+		return err
+	}
+
+	e.rend = rend.Render(root, node)
+	e.dirty = false
+
+	nested := map[int64]bool{}
+	for cid := range root.Components {
+		if before[cid] {
+			continue
+		}
+		if child := r.byCID[cid]; child != nil {
+			for _, c := range r.descendants(child) {
+				nested[c] = true
+			}
+		}
+	}
+
+	e.owned = map[int64]*rend.Rend{}
+	for cid, c := range root.Components {
+		if before[cid] || nested[cid] {
+			continue
+		}
+		e.owned[cid] = c
+		r.owners[cid] = e.cid
+	}
+
+	root.SetComponent(e.cid, e.rend)
+
+	return nil
+}
+
+// rerender renders a single component after it handled an event.
+func (r *components) rerender(tree *rend.Root, e *componentEntry) (*rend.Root, error) {
+	r.pinned = map[int64]*rend.Rend{}
+	defer func() {
+		r.pinned = nil
+	}()
+
+	for cid, c := range tree.Components {
+		if _, ok := e.owned[cid]; !ok {
+			r.pinned[cid] = c
+		}
+	}
+
+	root := rend.NewRootWith(r)
+
+	e.dirty = true
+
+	err := r.render(root, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
+
+func (r *components) restore(root *rend.Root, e *componentEntry) {
+	e.destroying = false
+
+	root.SetComponent(e.cid, e.rend)
+
+	for cid, c := range e.owned {
+		if child := r.byCID[cid]; child != nil {
+			r.restore(root, child)
+			continue
+		}
+		root.SetComponent(cid, c)
+	}
+}
+
+// release frees the cids owned by e ahead of a re-render.
+func (r *components) release(e *componentEntry) {
+	for cid := range e.owned {
+		if r.owners[cid] == e.cid {
+			delete(r.owners, cid)
+		}
+	}
+}
+
+func (r *components) descendants(e *componentEntry) []int64 {
+	cids := []int64{}
+	for cid := range e.owned {
+		cids = append(cids, cid)
+		if child := r.byCID[cid]; child != nil {
+			cids = append(cids, r.descendants(child)...)
+		}
+	}
+	return cids
+}
+
+func (r *components) nextCID(root *rend.Root) int64 {
+	for {
+		cid := r.ref.NextRef()
+		if _, ok := root.Components[cid]; ok {
+			continue
+		}
+		if r.Reserved(cid) {
+			continue
+		}
+		return cid
+	}
+}
+
+// willDestroy marks cids the client is about to remove. Components that
+// are rendered again before they are destroyed are kept.
+func (r *components) willDestroy(cids []int) {
+	for _, cid := range cids {
+		if e := r.byCID[int64(cid)]; e != nil {
+			e.destroying = true
+		}
+	}
+}
+
+// destroy unmounts the marked components in cids, reporting whether cid
+// can be pruned from the tree.
+func (r *components) destroy(cid int64) (bool, error) {
+	e := r.byCID[cid]
+	if e == nil {
+		delete(r.owners, cid)
+		return true, nil
+	}
+
+	if !e.destroying {
+		return false, nil
+	}
+
+	r.release(e)
+	delete(r.byCID, cid)
+	delete(r.byID, e.id)
+
+	return true, TryUnmount(e.view)
+}
+
+func (r *components) unmountAll() error {
+	for _, e := range r.byCID {
+		err := TryUnmount(e.view)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// snapshot copies the props a component was rendered with, deep enough
+// that changes made inside maps and slices in place are still noticed.
+func snapshot(v any) any {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	return deepCopy(rv, map[uintptr]reflect.Value{}).Interface()
+}
+
+// deepCopy copies maps, slices and pointers reachable from v. Unexported
+// struct fields can't be set through reflection, so they're shared.
+func deepCopy(v reflect.Value, seen map[uintptr]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		if c, ok := seen[v.Pointer()]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[v.Pointer()] = c
+		c.Elem().Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopy(v.Elem(), seen))
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), deepCopy(iter.Value(), seen))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopy(v.Index(i), seen))
+		}
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := c.Field(i); f.CanSet() {
+				f.Set(deepCopy(v.Field(i), seen))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// sameValue reports whether a and b point at the same component, which
+// happens when the parent keeps its components around between renders.
+func sameValue(a, b any) bool {
+	ra, rb := reflect.ValueOf(a), reflect.ValueOf(b)
+	return ra.Kind() == reflect.Pointer && rb.Kind() == reflect.Pointer &&
+		ra.Type() == rb.Type() && ra.Pointer() == rb.Pointer()
+}
@@ -0,0 +1,42 @@
+package liveview
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sethpollack/go-live-view/rend"
+)
+
+const csrfKey = "csrf_token"
+
+type csrfContextKey struct{}
+
+// WithCSRFToken stores the masked csrf token for the page being rendered.
+func WithCSRFToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, csrfContextKey{}, token)
+}
+
+// CSRFToken returns the masked csrf token for the page being rendered.
+func CSRFToken(ctx context.Context) string {
+	token, _ := ctx.Value(csrfContextKey{}).(string)
+	return token
+}
+
+type csrfMeta struct{}
+
+// CSRFMeta renders <meta name="csrf-token"> for layouts. The client sends
+// its content back as the _csrf_token socket param.
+func CSRFMeta() rend.Node {
+	return &csrfMeta{}
+}
+
+func (m *csrfMeta) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Builder) error {
+	token, _ := root.Value(csrfKey).(string)
+	if token == "" {
+		return nil
+	}
+
+	_, err := b.Write([]byte(fmt.Sprintf(`<meta name="csrf-token" content="%s">`, rend.EscapeAttr(token))))
+	return err
+}
@@ -0,0 +1,101 @@
+package liveview
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sethpollack/go-live-view/rend"
+	"github.com/sethpollack/go-live-view/std"
+)
+
+const flashValueKey = "flash"
+
+// clearFlashEvent is sent by the client to clear a flash, with the kind in
+// the key value. Without a key every flash is cleared.
+const clearFlashEvent = "lv:clear-flash"
+
+// Flash is the messages shown to the user, by kind like "info" or "error".
+type Flash map[string]string
+
+// Get returns the message for kind.
+func (f Flash) Get(kind string) string {
+	return f[kind]
+}
+
+// Kinds returns the kinds with a message, sorted.
+func (f Flash) Kinds() []string {
+	kinds := make([]string, 0, len(f))
+	for kind := range f {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func (f Flash) clear(kinds ...string) {
+	if len(kinds) == 0 {
+		for kind := range f {
+			delete(f, kind)
+		}
+		return
+	}
+
+	for _, kind := range kinds {
+		delete(f, kind)
+	}
+}
+
+func (f Flash) merge(other map[string]any) {
+	for kind, msg := range other {
+		if s, ok := msg.(string); ok {
+			f[kind] = s
+		}
+	}
+}
+
+// encodeFlash signs flash for the trip through a redirect, in the flash
+// cookie or the join params, so clients can't show their own messages.
+func encodeFlash(t tokenizer, flash map[string]any) (string, error) {
+	if t == nil {
+		return "", fmt.Errorf("flash can't be signed without a tokenizer")
+	}
+
+	return t.Encode(flashSalt, flash)
+}
+
+// decodeFlash verifies a flash signed by encodeFlash. Anything else,
+// including a flash that was altered, is dropped.
+func decodeFlash(t tokenizer, v any) map[string]any {
+	token, ok := v.(string)
+	if !ok || token == "" || t == nil {
+		return nil
+	}
+
+	flash := map[string]any{}
+	err := t.Decode(flashSalt, token, &flash)
+	if err != nil {
+		return nil
+	}
+
+	return flash
+}
+
+type flashNode struct {
+	render func(Flash) rend.Node
+}
+
+// RenderFlash renders f with the current flash, for views and layouts.
+func RenderFlash(f func(Flash) rend.Node) rend.Node {
+	return &flashNode{render: f}
+}
+
+func (n *flashNode) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Builder) error {
+	flash, _ := root.Value(flashValueKey).(Flash)
+	if flash == nil {
+		flash = Flash{}
+	}
+
+	// dynamic so messages coming and going don't change the statics.
+	return std.DynamicNode(n.render(flash)).Render(diff, root, t, b)
+}
@@ -1,14 +1,13 @@
 package liveview
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/rs/xid"
+	"github.com/sethpollack/go-live-view/channel"
 	"github.com/sethpollack/go-live-view/html"
 	"github.com/sethpollack/go-live-view/params"
 	"github.com/sethpollack/go-live-view/rend"
@@ -18,11 +17,21 @@ const flashKey = "__phoenix_flash__"
 
 var NotFoundError = errors.New("route not found")
 
+// ErrInvalidToken and ErrExpiredToken are returned by tokenizers. Joins
+// with either are rejected, and the error text is the reason the client
+// falls back to a full page load on.
+var (
+	ErrInvalidToken = errors.New("unauthorized")
+	ErrExpiredToken = errors.New("stale")
+)
+
 type Route interface {
 	GetView() View
 	GetParams() params.Params
 	GetHttpMounts() []func(http.ResponseWriter, *http.Request, params.Params) error
 	GetMounts() []func(Socket, params.Params) error
+	GetOnMount() []OnMount
+	GetLayout() func(...rend.Node) rend.Node
 }
 
 type Router interface {
@@ -35,17 +44,31 @@ type sessionGetter interface {
 	Get(*http.Request) map[string]any
 }
 
+// tokenizer seals values for a purpose named by salt, so a token issued for
+// one purpose doesn't verify as another.
 type tokenizer interface {
-	Encode(any) (string, error)
-	Decode(string, any) error
+	Encode(salt string, v any) (string, error)
+	Decode(salt, token string, v any) error
 }
 
+// salts of the tokens the lifecycle issues.
+const (
+	sessionSalt = "live_view session"
+	staticSalt  = "live_view static"
+	flashSalt   = "live_view flash"
+)
+
 type lifecycle struct {
 	router    Router
 	route     Route
 	tree      *rend.Root
+	comps     *components
 	tokenizer tokenizer
 	session   sessionGetter
+	flash     Flash
+
+	// joined is the session decoded on join.
+	joined map[string]any
 
 	firstJoin bool
 }
@@ -59,6 +82,8 @@ func NewLifecycle(
 		router:    r,
 		tokenizer: tokenizer,
 		session:   session,
+		comps:     newComponents(),
+		flash:     Flash{},
 		firstJoin: true,
 	}
 }
@@ -79,21 +104,86 @@ func (l *lifecycle) Join(s Socket, p params.Params) (*rend.Root, error) {
 		return nil, fmt.Errorf("cant patch to %s, redirecting", url)
 	}
 
+	page, err := l.decodeSession(p)
+	if err != nil {
+		return nil, err
+	}
+
+	// the route's middleware only ran for the page, so the socket can only
+	// join routes the page could navigate to without a page load.
+	if l.tokenizer != nil {
+		rendered, err := l.router.GetRoute(page.Path)
+		if err != nil || !l.router.Routable(rendered, route) {
+			joining(s)
+
+			err := s.Redirect(url)
+			if err != nil {
+				return nil, err
+			}
+
+			return nil, refuse(s)
+		}
+	}
+
 	l.route = route
 
+	err = l.comps.unmountAll()
+	if err != nil {
+		return nil, err
+	}
+	l.comps = newComponents()
+
 	view := route.GetView()
 
+	l.joined = page.Session
+
 	p = params.Merge(
 		p,
 		route.GetParams(),
-		l.decodeSession(p),
+		page.Session,
 	)
 
+	// the flash comes signed in the join params after a live redirect, or
+	// in the static token after a page load.
+	flash := decodeFlash(l.tokenizer, p["flash"])
+	delete(p, "flash")
+
 	if l.firstJoin {
-		p = params.Merge(p, l.decodeStatic(p))
+		static, err := l.decodeStatic(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if f, ok := static["flash"].(map[string]any); ok {
+			flash = f
+		}
+
+		p = params.Merge(p, static)
 		l.firstJoin = false
 	}
 
+	l.flash = Flash{}
+	if flash != nil {
+		l.flash.merge(flash)
+		p["flash"] = flash
+	}
+	l.attach(s)
+	joining(s)
+
+	halt, err := runOnMount(route.GetOnMount(), s, p, page.Session)
+	if err != nil {
+		return nil, err
+	}
+
+	if halt != nil {
+		err := s.Redirect(halt.To)
+		if err != nil {
+			return nil, err
+		}
+
+		return nil, refuse(s)
+	}
+
 	for _, mount := range route.GetMounts() {
 		err = mount(s, p)
 		if err != nil {
@@ -112,7 +202,7 @@ func (l *lifecycle) Join(s Socket, p params.Params) (*rend.Root, error) {
 	}
 
 	if s.Redirected() {
-		return nil, nil
+		return nil, refuse(s)
 	}
 
 	node, err := view.Render(nil)
@@ -120,9 +210,9 @@ func (l *lifecycle) Join(s Socket, p params.Params) (*rend.Root, error) {
 		return nil, err
 	}
 
-	l.tree = rend.RenderTree(node)
+	l.tree = l.renderTree(s, node)
 
-	return l.tree, nil
+	return send(s, l.tree, nil), nil
 }
 
 func (l *lifecycle) Params(s Socket, p params.Params) (*rend.Root, error) {
@@ -145,6 +235,8 @@ func (l *lifecycle) Params(s Socket, p params.Params) (*rend.Root, error) {
 
 	view := route.GetView()
 
+	l.attach(s)
+
 	p = params.Merge(
 		p,
 		route.GetParams(),
@@ -156,7 +248,7 @@ func (l *lifecycle) Params(s Socket, p params.Params) (*rend.Root, error) {
 	}
 
 	if s.Redirected() {
-		return nil, nil
+		return nil, release(s)
 	}
 
 	node, err := view.Render(nil)
@@ -164,18 +256,33 @@ func (l *lifecycle) Params(s Socket, p params.Params) (*rend.Root, error) {
 		return nil, err
 	}
 
-	newTree := rend.RenderTree(node)
+	newTree := l.renderTree(s, node)
 
 	diff := l.tree.Diff(newTree)
 
 	l.tree = newTree
 
-	return diff, nil
+	return send(s, diff, nil), nil
 }
 
 func (l *lifecycle) Event(s Socket, p params.Params) (*rend.Root, error) {
 	event := p.String("event")
 
+	p, err := decodeEvent(p)
+	if err != nil {
+		return nil, err
+	}
+
+	l.attach(s)
+
+	if event == clearFlashEvent {
+		return l.clearFlash(s, p)
+	}
+
+	if _, ok := p["cid"]; ok {
+		return l.componentEvent(s, int64(p.Int("cid")), event, p)
+	}
+
 	view := l.route.GetView()
 
 	p = params.Merge(
@@ -183,37 +290,142 @@ func (l *lifecycle) Event(s Socket, p params.Params) (*rend.Root, error) {
 		l.route.GetParams(),
 	)
 
-	if err := TryEvent(view, s, event, p); err != nil {
+	reply, err := TryEventReply(view, s, event, p)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Redirected() {
+		return nil, release(s)
+	}
+
+	node, err := view.Render(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	newTree := l.renderTree(s, node)
+
+	diff := l.tree.Diff(newTree)
+
+	l.tree = newTree
+
+	return send(s, diff, reply), nil
+}
+
+// decodeEvent decodes the URL encoded value of form events into nested
+// params, with the event's phx-value-* attributes from meta merged in.
+func decodeEvent(p params.Params) (params.Params, error) {
+	if p.String("type") != "form" {
+		return p, nil
+	}
+
+	encoded, ok := p["value"].(string)
+	if !ok {
+		return p, nil
+	}
+
+	value, err := params.Decode(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	// the client sends _target in meta, with the input name as its value.
+	for k, v := range p.Map("meta") {
+		value[k] = v
+	}
+	value.DecodeTarget()
+
+	p = params.Merge(p)
+	p["value"] = map[string]any(value)
+
+	return p, nil
+}
+
+func (l *lifecycle) componentEvent(s Socket, cid int64, event string, p params.Params) (*rend.Root, error) {
+	e := l.comps.get(cid)
+	if e == nil {
+		return nil, fmt.Errorf("component with cid %d not found", cid)
+	}
+
+	reply, err := TryEventReply(e.view, s, event, p)
+	if err != nil {
 		return nil, err
 	}
 
 	if s.Redirected() {
+		return nil, release(s)
+	}
+
+	l.comps.socket = s
+
+	newTree, err := l.comps.rerender(l.tree, e)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := l.tree.DiffComponents(newTree)
+
+	for cid, c := range newTree.Components {
+		l.tree.SetComponent(cid, c)
+	}
+
+	return send(s, diff, reply), nil
+}
+
+func (l *lifecycle) Info(s Socket, msg any) (*rend.Root, error) {
+	if l.route == nil {
 		return nil, nil
 	}
 
+	view := l.route.GetView()
+
+	l.attach(s)
+
+	if err := TryInfo(view, s, msg); err != nil {
+		return nil, err
+	}
+
+	if s.Redirected() {
+		return nil, release(s)
+	}
+
 	node, err := view.Render(nil)
 	if err != nil {
 		return nil, err
 	}
 
-	newTree := rend.RenderTree(node)
+	newTree := l.renderTree(s, node)
 
 	diff := l.tree.Diff(newTree)
 
 	l.tree = newTree
 
-	return diff, nil
+	return send(s, diff, nil), nil
 }
 
 func (l *lifecycle) StaticRender(w http.ResponseWriter, r *http.Request) (string, error) {
 	route, err := l.router.GetRoute(r.URL.String())
 	if err != nil {
+		if errors.Is(err, NotFoundError) {
+			w.WriteHeader(http.StatusNotFound)
+		}
 		return render404String(route, err)
 	}
 
 	view := route.GetView()
 
-	p := route.GetParams()
+	// copied so hooks can't write to the route.
+	p := params.Merge(route.GetParams())
+
+	halt, err := runOnMount(route.GetOnMount(), nil, p, l.session.Get(r))
+	if err != nil {
+		return "", err
+	}
+
+	if halt != nil {
+		return "", halt
+	}
 
 	for _, mount := range route.GetHttpMounts() {
 		err = mount(w, r, p)
@@ -242,37 +454,172 @@ func (l *lifecycle) StaticRender(w http.ResponseWriter, r *http.Request) (string
 		return "", err
 	}
 
-	return rend.RenderString(
-		l.router.GetLayout()(
+	flash := l.readFlash(w, r)
+
+	return rend.RenderStringWith(
+		route.GetLayout()(
 			html.Attrs(
 				html.DataAttr("phx-main"),
 				html.DataAttr("phx-session", l.encodeSession(r)),
-				html.DataAttr("phx-static", l.encodeStatic(w, r)),
+				html.DataAttr("phx-static", l.encodeStatic(flash)),
 				html.IdAttr(
 					fmt.Sprintf("phx-%s", xid.New().String()),
 				),
 			),
 			node,
 		),
+		map[string]any{
+			csrfKey:       CSRFToken(r.Context()),
+			titleKey:      TryTitle(view),
+			flashValueKey: Flash(flash),
+		},
 	), nil
 }
 
-func (l *lifecycle) DestroyCIDs(cids []int) error {
-	if l.tree.Components != nil {
-		for _, cid := range cids {
-			if l.tree.Components[int64(cid)] != nil {
-				return fmt.Errorf("component with cid %d found", cid)
-			}
+// WillDestroyCIDs marks components the client is about to remove.
+func (l *lifecycle) WillDestroyCIDs(cids []int) error {
+	l.comps.willDestroy(cids)
+
+	return nil
+}
+
+// DestroyCIDs removes components the client has removed, returning the
+// cids that were destroyed. Components rendered again since
+// WillDestroyCIDs are kept.
+func (l *lifecycle) DestroyCIDs(cids []int) ([]int, error) {
+	destroyed := []int{}
+
+	for _, cid := range cids {
+		ok, err := l.comps.destroy(int64(cid))
+		if err != nil {
+			return nil, err
 		}
+
+		if !ok {
+			continue
+		}
+
+		if l.tree != nil {
+			delete(l.tree.Components, int64(cid))
+		}
+
+		destroyed = append(destroyed, cid)
 	}
 
-	return nil
+	return destroyed, nil
 }
 
 func (l *lifecycle) Leave() error {
+	err := l.comps.unmountAll()
+	if err != nil {
+		return err
+	}
+
 	return TryUnmount(l.route.GetView())
 }
 
+// attach shares the liveview's flash and session with s, and the
+// tokenizer the flash is signed with. Events pushed to s are held for the
+// render.
+func (l *lifecycle) attach(s Socket) {
+	if s, ok := s.(*socket); ok {
+		s.flash = l.flash
+		s.tokenizer = l.tokenizer
+		s.session = l.joined
+		s.buffer()
+	}
+}
+
+// clearFlash handles lv:clear-flash, clearing the flash for the key value,
+// or every flash without one.
+func (l *lifecycle) clearFlash(s Socket, p params.Params) (*rend.Root, error) {
+	if key := p.Map("value").String("key"); key != "" {
+		l.flash.clear(key)
+	} else {
+		l.flash.clear()
+	}
+
+	node, err := l.route.GetView().Render(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	newTree := l.renderTree(s, node)
+
+	diff := l.tree.Diff(newTree)
+
+	l.tree = newTree
+
+	return send(s, diff, nil), nil
+}
+
+// send adds the events pushed to s and the event's reply to root, the
+// render they're sent with.
+func send(s Socket, root *rend.Root, reply any) *rend.Root {
+	var events [][]any
+	if s, ok := s.(*socket); ok {
+		events = s.flush()
+	}
+
+	if len(events) == 0 && reply == nil {
+		return root
+	}
+
+	// copied so they aren't kept on the tree diffs are made against.
+	sent := rend.Root{}
+	if root != nil {
+		sent = *root
+	}
+	sent.Events = events
+	sent.Reply = reply
+
+	return &sent
+}
+
+// release sends the events pushed to s on their own, for callbacks that
+// don't render, like ones that redirect.
+func release(s Socket) error {
+	if s, ok := s.(*socket); ok {
+		return s.release()
+	}
+
+	return nil
+}
+
+// joining holds redirects for the join's reply.
+func joining(s Socket) {
+	if s, ok := s.(*socket); ok {
+		s.joining = true
+	}
+}
+
+// refuse ends a join that redirected, refusing it with the redirect as
+// Phoenix does, since the client ignores redirects from a successful join.
+func refuse(s Socket) error {
+	ls, ok := s.(*socket)
+	if !ok || ls.redirect == nil {
+		return release(s)
+	}
+
+	// the liveview is gone, so are its events.
+	ls.flush()
+
+	return &channel.ReplyError{Response: ls.redirect}
+}
+
+func (l *lifecycle) renderTree(s Socket, node rend.Node) *rend.Root {
+	l.comps.socket = s
+
+	root := rend.RenderTreeWithValues(node, l.comps, map[string]any{
+		flashValueKey: l.flash,
+	})
+	if title := TryTitle(l.route.GetView()); title != "" {
+		root.Title = &title
+	}
+
+	return root
+}
+
 func (l *lifecycle) AllowUpload(s Socket, p params.Params) (any, error) {
 	ref := p.String("ref")
 
@@ -295,7 +642,7 @@ func (l *lifecycle) AllowUpload(s Socket, p params.Params) (any, error) {
 		return nil, err
 	}
 
-	newTree := rend.RenderTree(node)
+	newTree := l.renderTree(s, node)
 
 	diff := l.tree.Diff(newTree)
 
@@ -351,6 +698,8 @@ func (l *lifecycle) Progress(s Socket, p params.Params) (*rend.Root, error) {
 
 	view := l.route.GetView()
 
+	l.attach(s)
+
 	u := TryUploads(view)
 	if u == nil {
 		return nil, fmt.Errorf("uploads not found")
@@ -371,17 +720,27 @@ func (l *lifecycle) Progress(s Socket, p params.Params) (*rend.Root, error) {
 		return nil, err
 	}
 
-	newTree := rend.RenderTree(node)
+	newTree := l.renderTree(s, node)
 
 	diff := l.tree.Diff(newTree)
 
 	l.tree = newTree
 
-	return diff, nil
+	return send(s, diff, nil), nil
+}
+
+// pageSession is the session token put in the page, bound to the path the
+// page was rendered for.
+type pageSession struct {
+	Session map[string]any `json:"session"`
+	Path    string         `json:"path"`
 }
 
 func (l *lifecycle) encodeSession(r *http.Request) string {
-	data, err := l.tokenizer.Encode(l.session.Get(r))
+	data, err := l.tokenizer.Encode(sessionSalt, pageSession{
+		Session: l.session.Get(r),
+		Path:    r.URL.Path,
+	})
 	if err != nil {
 		return ""
 	}
@@ -389,29 +748,49 @@ func (l *lifecycle) encodeSession(r *http.Request) string {
 	return data
 }
 
-func (l *lifecycle) decodeSession(p params.Params) map[string]any {
-	decode := map[string]any{}
+// decodeSession reads the page's session token, which is required when
+// the lifecycle has a tokenizer.
+func (l *lifecycle) decodeSession(p params.Params) (pageSession, error) {
+	page := pageSession{}
 
 	session := p.String("session")
+	delete(p, "session")
+
+	if l.tokenizer == nil {
+		page.Session = map[string]any{}
+		return page, nil
+	}
+
 	if session == "" {
-		return decode
+		return page, ErrInvalidToken
 	}
-	delete(p, "session")
 
-	err := l.tokenizer.Decode(session, &decode)
+	err := l.tokenizer.Decode(sessionSalt, session, &page)
 	if err != nil {
-		return nil
+		return page, tokenError(err)
 	}
 
-	return decode
+	if page.Session == nil {
+		page.Session = map[string]any{}
+	}
+
+	return page, nil
 }
 
-func (l *lifecycle) encodeStatic(w http.ResponseWriter, r *http.Request) string {
-	encode := map[string]any{}
+// readFlash reads the flash the client stored in a cookie on redirect,
+// clearing the cookie so it's only shown once.
+func (l *lifecycle) readFlash(w http.ResponseWriter, r *http.Request) map[string]string {
+	flash := map[string]string{}
 
-	if cookie, err := r.Cookie(flashKey); err == nil {
-		flash, _ := base64.StdEncoding.DecodeString(cookie.Value)
-		encode["flash"] = flash
+	cookie, err := r.Cookie(flashKey)
+	if err != nil {
+		return flash
+	}
+
+	for kind, msg := range decodeFlash(l.tokenizer, cookie.Value) {
+		if s, ok := msg.(string); ok {
+			flash[kind] = s
+		}
 	}
 
 	http.SetCookie(w, &http.Cookie{
@@ -421,7 +800,17 @@ func (l *lifecycle) encodeStatic(w http.ResponseWriter, r *http.Request) string
 		Path:    "/",
 	})
 
-	data, err := l.tokenizer.Encode(encode)
+	return flash
+}
+
+func (l *lifecycle) encodeStatic(flash map[string]string) string {
+	encode := map[string]any{}
+
+	if len(flash) > 0 {
+		encode["flash"] = flash
+	}
+
+	data, err := l.tokenizer.Encode(staticSalt, encode)
 	if err != nil {
 		return ""
 	}
@@ -429,44 +818,31 @@ func (l *lifecycle) encodeStatic(w http.ResponseWriter, r *http.Request) string
 	return data
 }
 
-func (l *lifecycle) decodeStatic(p params.Params) map[string]any {
+func (l *lifecycle) decodeStatic(p params.Params) (map[string]any, error) {
 	decode := map[string]any{}
 
 	static := p.String("static")
 	if static == "" {
-		return decode
+		return decode, nil
 	}
 	delete(p, "static")
 
-	err := l.tokenizer.Decode(static, &decode)
+	err := l.tokenizer.Decode(staticSalt, static, &decode)
 	if err != nil {
-		return nil
+		return nil, tokenError(err)
 	}
 
-	decodeFlash(decode)
-
-	return decode
+	return decode, nil
 }
 
-func decodeFlash(m map[string]any) {
-	flash, ok := m["flash"]
-	if !ok {
-		return
-	}
-	delete(m, "flash")
-
-	decoded, err := base64.StdEncoding.DecodeString(flash.(string))
-	if err != nil {
-		return
-	}
-
-	var flashMap map[string]any
-	err = json.Unmarshal(decoded, &flashMap)
-	if err != nil {
-		return
+// tokenError reports any token that doesn't decode as invalid, so forged
+// tokens are rejected whatever the tokenizer returned.
+func tokenError(err error) error {
+	if errors.Is(err, ErrExpiredToken) {
+		return ErrExpiredToken
 	}
 
-	m["flash"] = flashMap
+	return ErrInvalidToken
 }
 
 func render404String(route Route, err error) (string, error) {
@@ -8,7 +8,7 @@ import (
 	"github.com/sethpollack/go-live-view/uploads"
 )
 
-var Version = "1.0.0-rc.6"
+var Version = "1.1.0"
 
 type View interface {
 	Render(rend.Node) (rend.Node, error)
@@ -34,10 +34,34 @@ type EventHandler interface {
 	Event(Socket, string, params.Params) error
 }
 
+// EventReplier is an EventHandler that replies to the client, for hooks
+// that push events with a callback:
+//
+//	this.pushEvent("geocode", {address}, (reply) => ...)
+//
+// The reply is sent along with the render the event causes, and should
+// encode to a JSON object. Views that implement EventReplier aren't sent
+// events through EventHandler.
+type EventReplier interface {
+	EventReply(Socket, string, params.Params) (any, error)
+}
+
+// InfoHandler receives server side messages sent with PushSelf and
+// PushBroadcast. Unlike events, clients can never trigger them.
+type InfoHandler interface {
+	Info(Socket, any) error
+}
+
 type Uploader interface {
 	Uploads() *uploads.Uploads
 }
 
+// Titler sets the page title. It's called after every render, and the
+// client is only sent the title when it changes.
+type Titler interface {
+	Title() string
+}
+
 func TryHttpMount(a any, w http.ResponseWriter, r *http.Request, p params.Params) error {
 	if m, ok := a.(HTTPMounter); ok {
 		return m.HttpMount(w, r, p)
@@ -78,6 +102,24 @@ func TryEvent(a any, s Socket, event string, p params.Params) error {
 	return nil
 }
 
+// TryEventReply sends the event to an EventReplier, or an EventHandler,
+// returning the reply if there is one.
+func TryEventReply(a any, s Socket, event string, p params.Params) (any, error) {
+	if m, ok := a.(EventReplier); ok {
+		return m.EventReply(s, event, p)
+	}
+
+	return nil, TryEvent(a, s, event, p)
+}
+
+func TryInfo(a any, s Socket, msg any) error {
+	if m, ok := a.(InfoHandler); ok {
+		return m.Info(s, msg)
+	}
+
+	return nil
+}
+
 func TryUploads(a any) *uploads.Uploads {
 	if m, ok := a.(Uploader); ok {
 		return m.Uploads()
@@ -85,3 +127,11 @@ func TryUploads(a any) *uploads.Uploads {
 
 	return nil
 }
+
+func TryTitle(a any) string {
+	if m, ok := a.(Titler); ok {
+		return m.Title()
+	}
+
+	return ""
+}
@@ -0,0 +1,50 @@
+package liveview
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/sethpollack/go-live-view/params"
+)
+
+// OnMount hooks are shared by the routes of a live session and run before
+// their views mount: once for the HTTP render, where s is nil, and again
+// when the socket joins. session is the decoded session. Values set on p
+// are passed on to the views' Mount.
+//
+// Returning nil continues the mount, Halt stops it and redirects, and any
+// other error stops it with that error.
+type OnMount func(s Socket, p params.Params, session map[string]any) error
+
+// HaltError stops a mount and redirects to To.
+type HaltError struct {
+	To string
+}
+
+func (e *HaltError) Error() string {
+	return fmt.Sprintf("halted, redirecting to %s", e.To)
+}
+
+// Halt stops the mount from an OnMount hook and redirects to url.
+func Halt(url string) error {
+	return &HaltError{To: url}
+}
+
+// runOnMount runs hooks in order, stopping at the first that halts.
+func runOnMount(hooks []OnMount, s Socket, p params.Params, session map[string]any) (*HaltError, error) {
+	for _, hook := range hooks {
+		err := hook(s, p, session)
+		if err == nil {
+			continue
+		}
+
+		var halt *HaltError
+		if errors.As(err, &halt) {
+			return halt, nil
+		}
+
+		return nil, err
+	}
+
+	return nil, nil
+}
@@ -1,8 +1,8 @@
 package liveview
 
 import (
-	"encoding/base64"
-	"encoding/json"
+	"fmt"
+	"sync"
 
 	"github.com/sethpollack/go-live-view/channel"
 )
@@ -14,15 +14,55 @@ type redirectOption func(map[string]any)
 type Socket interface {
 	channel.Socket
 	PushEvent(string, any) error
+	PushEvents(...ClientEvent) error
 	PushPatch(string, ...redirectOption) error
 	PushNavigate(string, ...redirectOption) error
 	Redirect(string, ...redirectOption) error
 	Redirected() bool
+	PutFlash(kind, msg string)
+	ClearFlash(kinds ...string)
+	Flash() Flash
+	Session() map[string]any
+}
+
+// Info is the message passed to InfoHandler for PushSelf and PushBroadcast.
+// Topic is only set for broadcasts. Payload keeps its Go type when
+// delivered in process, and is decoded JSON when it comes from another node.
+type Info struct {
+	Topic   string `json:"topic,omitempty"`
+	Event   string `json:"event"`
+	Payload any    `json:"payload"`
+}
+
+// ClientEvent is an event pushed to the client, handled by hooks with
+// handleEvent or by window listeners as phx:<event>.
+type ClientEvent struct {
+	Event   string
+	Payload any
 }
 
 type socket struct {
 	channel.Socket
 	redirected bool
+
+	// events pushed while a render is in flight are sent with it, and
+	// pushed on their own otherwise.
+	mu        sync.Mutex
+	buffering bool
+	events    [][]any
+
+	// flash is the liveview's flash, shared by its sockets, and tokenizer
+	// signs it for redirects.
+	flash     Flash
+	tokenizer tokenizer
+
+	// session is the session the liveview joined with.
+	session map[string]any
+
+	// joining holds a redirect for the join's reply, the client only
+	// follows it when the join is refused.
+	joining  bool
+	redirect map[string]any
 }
 
 func NewSocket(s channel.Socket) *socket {
@@ -31,11 +71,16 @@ func NewSocket(s channel.Socket) *socket {
 	}
 }
 
+// WithFlash puts a flash for the page being navigated to. The socket's
+// flash is also carried over by PushNavigate and Redirect.
 func WithFlash(key, value string) redirectOption {
 	return func(m map[string]any) {
-		m["flash"] = map[string]string{
-			key: value,
+		flash, _ := m["flash"].(map[string]any)
+		if flash == nil {
+			flash = map[string]any{}
 		}
+		flash[key] = value
+		m["flash"] = flash
 	}
 }
 
@@ -45,37 +90,82 @@ func WithReplace() redirectOption {
 	}
 }
 
-// PushSelf sends payload back to the mounted liveview.
+// PushSelf sends an Info message back to the mounted liveview.
 func (s *socket) PushSelf(event string, payload any) error {
-	return s.Socket.PushSelf("event",
-		map[string]any{
-			"event": event,
-			"type":  "self",
-			"value": payload,
-		},
-	)
-}
-
-// PushBroadcast sends payload to all liveviews.
-func (s *socket) PushBroadcast(event string, payload any) error {
-	return s.Socket.PushBroadcast("event",
-		map[string]any{
-			"event": event,
-			"type":  "broadcast",
-			"value": payload,
-		},
-	)
-}
-
-// PushEvent sends an event to the client.
-func (s *socket) PushEvent(event string, payload any) error {
-	return s.Push("e", [][]any{
-		{
-			event, payload,
-		},
+	return s.Socket.PushSelf("info", Info{
+		Event:   event,
+		Payload: payload,
 	})
 }
 
+// PushBroadcast sends an Info message to all liveviews subscribed to topic.
+func (s *socket) PushBroadcast(topic string, event string, payload any) error {
+	return s.Socket.PushBroadcast(topic, "info", Info{
+		Topic:   topic,
+		Event:   event,
+		Payload: payload,
+	})
+}
+
+// PushEvent sends an event to the client. Events pushed from a callback
+// are sent with its render, and others are sent right away, in the order
+// they're pushed.
+func (s *socket) PushEvent(event string, payload any) error {
+	return s.push([][]any{{event, payload}})
+}
+
+// PushEvents sends events to the client together, like PushEvent.
+func (s *socket) PushEvents(events ...ClientEvent) error {
+	pushed := make([][]any, len(events))
+	for i, e := range events {
+		pushed[i] = []any{e.Event, e.Payload}
+	}
+
+	return s.push(pushed)
+}
+
+func (s *socket) push(events [][]any) error {
+	s.mu.Lock()
+	if s.buffering {
+		s.events = append(s.events, events...)
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	// sent through the liveview so they're ordered with its renders.
+	return s.Socket.PushSelf("push_events", events)
+}
+
+// buffer holds the events pushed from now on for the render.
+func (s *socket) buffer() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buffering = true
+}
+
+// flush stops holding events, returning the ones pushed.
+func (s *socket) flush() [][]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := s.events
+	s.events = nil
+	s.buffering = false
+
+	return events
+}
+
+// release stops holding events, pushing the ones held on their own.
+func (s *socket) release() error {
+	return s.push(s.flush())
+}
+
 // PushPatch sends a live_patch to the client.
 func (s *socket) PushPatch(url string, opts ...redirectOption) error {
 	payload := map[string]any{
@@ -87,6 +177,14 @@ func (s *socket) PushPatch(url string, opts ...redirectOption) error {
 		opt(payload)
 	}
 
+	// the liveview stays mounted, so its flash is kept as is.
+	if flash, ok := payload["flash"].(map[string]any); ok {
+		for kind, msg := range flash {
+			s.PutFlash(kind, fmt.Sprint(msg))
+		}
+		delete(payload, "flash")
+	}
+
 	err := s.Push("live_patch", payload)
 	if err != nil {
 		return err
@@ -94,9 +192,8 @@ func (s *socket) PushPatch(url string, opts ...redirectOption) error {
 
 	// client does not return an event, so we push it to ourselves
 	err = s.Socket.PushSelf("live_patch", map[string]any{
-		"kind":  payload["kind"],
-		"url":   payload["to"],
-		"flash": payload["flash"],
+		"kind": payload["kind"],
+		"url":  payload["to"],
 	})
 	if err != nil {
 		return err
@@ -108,40 +205,38 @@ func (s *socket) PushPatch(url string, opts ...redirectOption) error {
 }
 
 // PushNavigate sends a live_redirect to the client.
-func (s socket) PushNavigate(url string, opts ...redirectOption) error {
+func (s *socket) PushNavigate(url string, opts ...redirectOption) error {
 	payload := map[string]any{
 		"to":   url,
 		"kind": "push",
 	}
 
-	for _, opt := range opts {
-		opt(payload)
-	}
-
-	err := s.Push("live_redirect", payload)
+	err := s.carryFlash(payload, opts)
 	if err != nil {
 		return err
 	}
 
-	s.redirected = true
-
-	return nil
+	return s.sendRedirect("live_redirect", payload)
 }
 
 // Redirect sends a redirect to the client.
-func (s socket) Redirect(url string, opts ...redirectOption) error {
+func (s *socket) Redirect(url string, opts ...redirectOption) error {
 	payload := map[string]any{
 		"to": url,
 	}
 
-	for _, opt := range opts {
-		opt(payload)
+	err := s.carryFlash(payload, opts)
+	if err != nil {
+		return err
 	}
 
-	encodeFlash(payload)
+	return s.sendRedirect("redirect", payload)
+}
 
-	err := s.Push("redirect", payload)
-	if err != nil {
+func (s *socket) sendRedirect(event string, payload map[string]any) error {
+	if s.joining {
+		s.redirect = map[string]any{event: payload}
+	} else if err := s.Push(event, payload); err != nil {
 		return err
 	}
 
@@ -155,17 +250,54 @@ func (s *socket) Redirected() bool {
 	return s.redirected
 }
 
-func encodeFlash(m map[string]any) {
-	flash, ok := m["flash"]
-	if !ok {
-		return
+// PutFlash shows msg as the kind flash, like "info" or "error".
+func (s *socket) PutFlash(kind, msg string) {
+	if s.flash == nil {
+		s.flash = Flash{}
+	}
+
+	s.flash[kind] = msg
+}
+
+// ClearFlash clears the flash for kinds, or every flash without kinds.
+func (s *socket) ClearFlash(kinds ...string) {
+	s.flash.clear(kinds...)
+}
+
+// Flash returns the current flash.
+func (s *socket) Flash() Flash {
+	return s.flash
+}
+
+// Session returns the session the liveview joined with, from the page's
+// session token.
+func (s *socket) Session() map[string]any {
+	return s.session
+}
+
+// carryFlash applies opts to payload, signing the socket's flash along
+// with any set by opts so it reaches the next page.
+func (s *socket) carryFlash(payload map[string]any, opts []redirectOption) error {
+	flash := map[string]any{}
+	for kind, msg := range s.flash {
+		flash[kind] = msg
 	}
-	delete(m, "flash")
+	payload["flash"] = flash
 
-	js, err := json.Marshal(flash)
+	for _, opt := range opts {
+		opt(payload)
+	}
+
+	if len(flash) == 0 {
+		delete(payload, "flash")
+		return nil
+	}
+
+	token, err := encodeFlash(s.tokenizer, flash)
 	if err != nil {
-		return
+		return err
 	}
+	payload["flash"] = token
 
-	m["flash"] = base64.StdEncoding.EncodeToString(js)
+	return nil
 }
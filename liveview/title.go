@@ -0,0 +1,73 @@
+package liveview
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sethpollack/go-live-view/rend"
+)
+
+const titleKey = "title"
+
+type titleOption func(*liveTitle)
+
+type liveTitle struct {
+	prefix   string
+	suffix   string
+	fallback string
+}
+
+// WithTitlePrefix is put before the page title.
+func WithTitlePrefix(prefix string) titleOption {
+	return func(t *liveTitle) {
+		t.prefix = prefix
+	}
+}
+
+// WithTitleSuffix is put after the page title.
+func WithTitleSuffix(suffix string) titleOption {
+	return func(t *liveTitle) {
+		t.suffix = suffix
+	}
+}
+
+// WithDefaultTitle is used when the view has no title.
+func WithDefaultTitle(title string) titleOption {
+	return func(t *liveTitle) {
+		t.fallback = title
+	}
+}
+
+// LiveTitle renders <title> for layouts with the title of the view being
+// rendered. The client keeps the prefix and suffix when the title changes.
+func LiveTitle(opts ...titleOption) rend.Node {
+	t := &liveTitle{}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+func (t *liveTitle) Render(diff bool, root *rend.Root, _ *rend.Rend, b *strings.Builder) error {
+	title, _ := root.Value(titleKey).(string)
+	if title == "" {
+		title = t.fallback
+	}
+
+	attrs := ""
+	for _, attr := range [][2]string{
+		{"prefix", t.prefix},
+		{"suffix", t.suffix},
+		{"default", t.fallback},
+	} {
+		if attr[1] != "" {
+			attrs += fmt.Sprintf(` data-%s="%s"`, attr[0], rend.EscapeAttr(attr[1]))
+		}
+	}
+
+	_, err := b.Write([]byte(fmt.Sprintf(
+		"<title%s>%s</title>", attrs, rend.EscapeText(t.prefix+title+t.suffix),
+	)))
+	return err
+}
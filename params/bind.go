@@ -0,0 +1,514 @@
+package params
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unmarshaler is implemented by types that decode themselves from a param
+// value, which is a string, number, bool, Params or []any.
+type Unmarshaler interface {
+	UnmarshalParam(any) error
+}
+
+// FieldError is the error binding a single field. Field is the param's
+// name, in form notation like user[address][zip].
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is every field that failed to bind.
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Field returns the error for field, or nil.
+func (e Errors) Field(field string) error {
+	for _, err := range e {
+		if err.Field == field {
+			return err.Err
+		}
+	}
+	return nil
+}
+
+// timeLayouts are tried in order for time.Time fields, covering the values
+// of date and datetime-local inputs.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"15:04:05",
+	"15:04",
+}
+
+var (
+	timeType        = reflect.TypeOf(time.Time{})
+	unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textType        = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Bind decodes p into the struct dst points to. Fields are matched by their
+// param tag, or by name ignoring case:
+//
+//	type Form struct {
+//		Name  string         `param:"name"`
+//		Age   *int           `param:"age"`
+//		Tags  []string       `param:"tags"`
+//		Birth time.Time      `param:"birth"`
+//		Extra map[string]int `param:"extra"`
+//		Skip  string         `param:"-"`
+//	}
+//
+// Params that are missing leave their field as is, and empty strings set
+// the zero value, or nil for pointers. The error is an Errors with every
+// field that failed.
+func Bind(p Params, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("params: bind needs a pointer to a struct, got %T", dst)
+	}
+
+	b := &binder{}
+	b.bindStruct(map[string]any(p), v.Elem(), "")
+
+	if len(b.errs) > 0 {
+		return b.errs
+	}
+
+	return nil
+}
+
+type binder struct {
+	errs Errors
+}
+
+func (b *binder) fail(field string, err error) {
+	b.errs = append(b.errs, &FieldError{Field: field, Err: err})
+}
+
+func (b *binder) bindStruct(m map[string]any, v reflect.Value, prefix string) {
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("param")
+		if tag == "-" {
+			continue
+		}
+
+		if f.Anonymous && tag == "" && f.Type.Kind() == reflect.Struct {
+			b.bindStruct(m, v.Field(i), prefix)
+			continue
+		}
+
+		name := tag
+		if name == "" {
+			name = f.Name
+		}
+
+		value, key, ok := lookup(m, name, tag == "")
+		if !ok {
+			continue
+		}
+
+		b.bind(value, v.Field(i), fieldName(prefix, key))
+	}
+}
+
+// lookup finds name in m, ignoring case when fold is set.
+func lookup(m map[string]any, name string, fold bool) (any, string, bool) {
+	if value, ok := m[name]; ok {
+		return value, name, true
+	}
+
+	if fold {
+		for key, value := range m {
+			if strings.EqualFold(key, name) {
+				return value, key, true
+			}
+		}
+	}
+
+	return nil, "", false
+}
+
+func fieldName(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "[" + key + "]"
+}
+
+func (b *binder) bind(value any, v reflect.Value, field string) {
+	if value == nil {
+		v.SetZero()
+		return
+	}
+
+	if v.Kind() == reflect.Pointer {
+		if s, ok := value.(string); ok && s == "" {
+			v.SetZero()
+			return
+		}
+
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+
+		b.bind(value, v.Elem(), field)
+		return
+	}
+
+	if v.CanAddr() {
+		addr := v.Addr()
+
+		if addr.Type().Implements(unmarshalerType) {
+			err := addr.Interface().(Unmarshaler).UnmarshalParam(value)
+			if err != nil {
+				b.fail(field, err)
+			}
+			return
+		}
+
+		if v.Type() != timeType && addr.Type().Implements(textType) {
+			if s, ok := value.(string); ok {
+				err := addr.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+				if err != nil {
+					b.fail(field, err)
+				}
+				return
+			}
+		}
+	}
+
+	if v.Type() == timeType {
+		t, err := parseTime(value)
+		if err != nil {
+			b.fail(field, err)
+			return
+		}
+		v.Set(reflect.ValueOf(t))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		rv := reflect.ValueOf(value)
+		if !rv.Type().AssignableTo(v.Type()) {
+			b.fail(field, fmt.Errorf("%T does not implement %s", value, v.Type()))
+			return
+		}
+		v.Set(rv)
+
+	case reflect.Struct:
+		m, ok := toMap(value)
+		if !ok {
+			b.fail(field, fmt.Errorf("expected a map, got %T", value))
+			return
+		}
+		b.bindStruct(m, v, field)
+
+	case reflect.Map:
+		b.bindMap(value, v, field)
+
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			if s, ok := value.(string); ok {
+				v.SetBytes([]byte(s))
+				return
+			}
+		}
+		b.bindSlice(value, v, field)
+
+	default:
+		err := setScalar(value, v)
+		if err != nil {
+			b.fail(field, err)
+		}
+	}
+}
+
+func (b *binder) bindMap(value any, v reflect.Value, field string) {
+	if v.Type().Key().Kind() != reflect.String {
+		b.fail(field, fmt.Errorf("unsupported map key %s", v.Type().Key()))
+		return
+	}
+
+	m, ok := toMap(value)
+	if !ok {
+		b.fail(field, fmt.Errorf("expected a map, got %T", value))
+		return
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(v.Type(), len(m)))
+	}
+
+	for key, value := range m {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		b.bind(value, elem, fieldName(field, key))
+		v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+	}
+}
+
+func (b *binder) bindSlice(value any, v reflect.Value, field string) {
+	items, keys := toSlice(value)
+
+	s := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		b.bind(item, s.Index(i), fieldName(field, keys[i]))
+	}
+
+	v.Set(s)
+}
+
+// toMap returns value as a map[string]any, if it's a map.
+func toMap(value any) (map[string]any, bool) {
+	switch m := value.(type) {
+	case Params:
+		return m, true
+	case map[string]any:
+		return m, true
+	case map[string]string:
+		result := make(map[string]any, len(m))
+		for k, v := range m {
+			result[k] = v
+		}
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+// toSlice returns the items of value along with their names. Maps with
+// index keys, like items[0][qty], are sorted by index, and single values
+// become a slice of one.
+func toSlice(value any) ([]any, []string) {
+	switch s := value.(type) {
+	case []any:
+		return s, indexes(len(s))
+	case []string:
+		items := make([]any, len(s))
+		for i, v := range s {
+			items[i] = v
+		}
+		return items, indexes(len(s))
+	}
+
+	if m, ok := toMap(value); ok {
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+
+		sort.Slice(keys, func(i, j int) bool {
+			a, errA := strconv.Atoi(keys[i])
+			b, errB := strconv.Atoi(keys[j])
+			if errA != nil || errB != nil {
+				return keys[i] < keys[j]
+			}
+			return a < b
+		})
+
+		items := make([]any, len(keys))
+		for i, k := range keys {
+			items[i] = m[k]
+		}
+		return items, keys
+	}
+
+	return []any{value}, indexes(1)
+}
+
+func indexes(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+	return keys
+}
+
+func parseTime(value any) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("expected a time, got %T", value)
+	}
+
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	for _, layout := range timeLayouts {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q", s)
+}
+
+func setScalar(value any, v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.String:
+		switch value.(type) {
+		case map[string]any, Params, []any:
+			return fmt.Errorf("expected a string, got %T", value)
+		}
+		v.SetString(Params{"v": value}.String("v"))
+
+	case reflect.Bool:
+		switch value := value.(type) {
+		case bool:
+			v.SetBool(value)
+		case string:
+			b, err := parseBool(value)
+			if err != nil {
+				return err
+			}
+			v.SetBool(b)
+		default:
+			return fmt.Errorf("expected a bool, got %T", value)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch value := value.(type) {
+		case string:
+			if value == "" {
+				v.SetZero()
+				return nil
+			}
+			n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+			if err != nil {
+				return numError(value, err)
+			}
+			i = n
+		case float64:
+			if value != float64(int64(value)) {
+				return fmt.Errorf("%v is not an integer", value)
+			}
+			i = int64(value)
+		case int:
+			i = int64(value)
+		case int64:
+			i = value
+		default:
+			return fmt.Errorf("expected an integer, got %T", value)
+		}
+		if v.OverflowInt(i) {
+			return fmt.Errorf("%d is out of range", i)
+		}
+		v.SetInt(i)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		switch value := value.(type) {
+		case string:
+			if value == "" {
+				v.SetZero()
+				return nil
+			}
+			n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+			if err != nil {
+				return numError(value, err)
+			}
+			u = n
+		case float64:
+			if value < 0 || value != float64(uint64(value)) {
+				return fmt.Errorf("%v is not an unsigned integer", value)
+			}
+			u = uint64(value)
+		case int:
+			if value < 0 {
+				return fmt.Errorf("%d is not an unsigned integer", value)
+			}
+			u = uint64(value)
+		default:
+			return fmt.Errorf("expected an unsigned integer, got %T", value)
+		}
+		if v.OverflowUint(u) {
+			return fmt.Errorf("%d is out of range", u)
+		}
+		v.SetUint(u)
+
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch value := value.(type) {
+		case string:
+			if value == "" {
+				v.SetZero()
+				return nil
+			}
+			n, err := strconv.ParseFloat(value, v.Type().Bits())
+			if err != nil {
+				return numError(value, err)
+			}
+			f = n
+		case float64:
+			f = value
+		case int:
+			f = float64(value)
+		case int64:
+			f = float64(value)
+		default:
+			return fmt.Errorf("expected a number, got %T", value)
+		}
+		v.SetFloat(f)
+
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// parseBool also accepts checkbox values.
+func parseBool(s string) (bool, error) {
+	switch s {
+	case "", "off":
+		return false, nil
+	case "on":
+		return true, nil
+	}
+
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid bool %q", s)
+	}
+	return b, nil
+}
+
+func numError(s string, err error) error {
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("%s is out of range", s)
+	}
+	return fmt.Errorf("invalid number %q", s)
+}
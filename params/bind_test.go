@@ -0,0 +1,234 @@
+package params
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type upper string
+
+func (u *upper) UnmarshalParam(v any) error {
+	s, ok := v.(string)
+	if !ok {
+		return errors.New("not a string")
+	}
+	*u = upper(strings.ToUpper(s))
+	return nil
+}
+
+type address struct {
+	City string `param:"city"`
+	Zip  int    `param:"zip"`
+}
+
+type item struct {
+	Name string `param:"name"`
+	Qty  int    `param:"qty"`
+}
+
+type Base struct {
+	ID int `param:"id"`
+}
+
+type bindForm struct {
+	Base
+
+	Name    string            `param:"name"`
+	Age     *int              `param:"age"`
+	Score   float64           `param:"score"`
+	Admin   bool              `param:"admin"`
+	Tags    []string          `param:"tags"`
+	Items   []item            `param:"items"`
+	Address address           `param:"address"`
+	Extra   map[string]int    `param:"extra"`
+	Born    time.Time         `param:"born"`
+	Code    upper             `param:"code"`
+	Raw     any               `param:"raw"`
+	Labels  map[string]string `param:"labels"`
+	Nick    string
+	Skip    string `param:"-"`
+}
+
+func TestBind(t *testing.T) {
+	age := 30
+
+	tt := []struct {
+		name     string
+		params   Params
+		expected bindForm
+		errs     map[string]string
+	}{
+		{
+			name: "strings",
+			params: Params{
+				"id":     "7",
+				"name":   "bob",
+				"age":    "30",
+				"score":  "1.5",
+				"admin":  "on",
+				"tags":   []any{"a", "b"},
+				"born":   "2024-02-03",
+				"code":   "abc",
+				"nick":   "bobby",
+				"Skip":   "x",
+				"raw":    "raw",
+				"labels": map[string]any{"a": "b"},
+			},
+			expected: bindForm{
+				Base:   Base{ID: 7},
+				Name:   "bob",
+				Age:    &age,
+				Score:  1.5,
+				Admin:  true,
+				Tags:   []string{"a", "b"},
+				Born:   time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC),
+				Code:   "ABC",
+				Nick:   "bobby",
+				Raw:    "raw",
+				Labels: map[string]string{"a": "b"},
+			},
+		},
+		{
+			name: "json",
+			params: Params{
+				"age":   float64(30),
+				"score": float64(2),
+				"admin": true,
+				"name":  float64(12),
+			},
+			expected: bindForm{
+				Name:  "12",
+				Age:   &age,
+				Score: 2,
+				Admin: true,
+			},
+		},
+		{
+			name: "nested",
+			params: Params{
+				"address": map[string]any{"city": "Paris", "zip": "75001"},
+				"items": map[string]any{
+					"1":  map[string]any{"name": "b", "qty": "2"},
+					"0":  map[string]any{"name": "a", "qty": "1"},
+					"10": map[string]any{"name": "c", "qty": "3"},
+				},
+				"extra": map[string]any{"x": "1"},
+			},
+			expected: bindForm{
+				Address: address{City: "Paris", Zip: 75001},
+				Items:   []item{{"a", 1}, {"b", 2}, {"c", 3}},
+				Extra:   map[string]int{"x": 1},
+			},
+		},
+		{
+			name: "single value slice",
+			params: Params{
+				"tags": "a",
+			},
+			expected: bindForm{
+				Tags: []string{"a"},
+			},
+		},
+		{
+			name: "empty values",
+			params: Params{
+				"age":   "",
+				"score": "",
+				"admin": "",
+				"born":  "",
+			},
+			expected: bindForm{},
+		},
+		{
+			name: "errors",
+			params: Params{
+				"id":      "x",
+				"age":     "old",
+				"admin":   "maybe",
+				"address": map[string]any{"zip": "abc"},
+				"items":   []any{map[string]any{"qty": "1.5"}},
+				"born":    "yesterday",
+				"code":    float64(1),
+				"name":    "bob",
+			},
+			expected: bindForm{
+				Name:  "bob",
+				Age:   new(int),
+				Items: []item{{}},
+			},
+			errs: map[string]string{
+				"id":            `invalid number "x"`,
+				"age":           `invalid number "old"`,
+				"admin":         `invalid bool "maybe"`,
+				"address[zip]":  `invalid number "abc"`,
+				"items[0][qty]": `invalid number "1.5"`,
+				"born":          `invalid time "yesterday"`,
+				"code":          "not a string",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var form bindForm
+			err := Bind(tc.params, &form)
+
+			assert.Equal(t, tc.expected, form)
+
+			if tc.errs == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var errs Errors
+			assert.ErrorAs(t, err, &errs)
+			assert.Len(t, errs, len(tc.errs))
+			for field, msg := range tc.errs {
+				assert.EqualError(t, errs.Field(field), msg, field)
+			}
+		})
+	}
+}
+
+func TestBindOverflow(t *testing.T) {
+	var dst struct {
+		Small int8  `param:"small"`
+		Count uint8 `param:"count"`
+	}
+
+	err := Bind(Params{"small": "300", "count": float64(-1)}, &dst)
+
+	var errs Errors
+	assert.ErrorAs(t, err, &errs)
+	assert.EqualError(t, errs.Field("small"), "300 is out of range")
+	assert.EqualError(t, errs.Field("count"), "-1 is not an unsigned integer")
+}
+
+func TestBindInterface(t *testing.T) {
+	var dst struct {
+		Name  fmt.Stringer `param:"name"`
+		Error error        `param:"error"`
+		Raw   any          `param:"raw"`
+	}
+
+	err := Bind(Params{"name": "bob", "error": nil, "raw": float64(1)}, &dst)
+
+	var errs Errors
+	assert.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs.Field("name"), "string does not implement fmt.Stringer")
+	assert.Nil(t, dst.Name)
+	assert.Nil(t, dst.Error)
+	assert.Equal(t, float64(1), dst.Raw)
+}
+
+func TestBindDestination(t *testing.T) {
+	var s string
+	assert.EqualError(t, Bind(Params{}, &s), "params: bind needs a pointer to a struct, got *string")
+	assert.EqualError(t, Bind(Params{}, bindForm{}), "params: bind needs a pointer to a struct, got params.bindForm")
+}
@@ -0,0 +1,180 @@
+package params
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Decode decodes a URL encoded form into nested params, the way Plug does:
+//
+//	user[name]=bob           {"user": {"name": "bob"}}
+//	tags[]=a&tags[]=b        {"tags": ["a", "b"]}
+//	items[][qty]=1           {"items": [{"qty": "1"}]}
+//	items[0][qty]=1          {"items": {"0": {"qty": "1"}}}
+//
+// A key in a list of maps that's already set on the last entry starts a new
+// entry. When a key is repeated the last value wins.
+func Decode(query string) (Params, error) {
+	p := Params{}
+
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+
+		key, value, _ := strings.Cut(pair, "=")
+
+		key, err := url.QueryUnescape(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", key, err)
+		}
+
+		value, err = url.QueryUnescape(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+
+		name, parts := splitKey(key)
+		if name == "" {
+			continue
+		}
+
+		assign(p, name, parts, value)
+	}
+
+	return p, nil
+}
+
+// DecodeForm is Decode, setting _target to its path.
+func DecodeForm(query string) (Params, error) {
+	p, err := Decode(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p.DecodeTarget()
+
+	return p, nil
+}
+
+// Target returns the path of the _target param sent with form changes, the
+// name of the input that changed, like ["user", "name"] for user[name].
+func (p Params) Target() []string {
+	switch v := p["_target"].(type) {
+	case string:
+		name, parts := splitKey(v)
+
+		target := []string{name}
+		for _, part := range parts {
+			if part != "" {
+				target = append(target, part)
+			}
+		}
+		return target
+	case []string:
+		return v
+	case []any:
+		target := make([]string, 0, len(v))
+		for _, t := range v {
+			target = append(target, fmt.Sprint(t))
+		}
+		return target
+	default:
+		return nil
+	}
+}
+
+// DecodeTarget replaces the _target input name with its path.
+func (p Params) DecodeTarget() {
+	if target := p.Target(); target != nil {
+		p["_target"] = target
+	}
+}
+
+// splitKey splits a[b][] into a and [b, ""]. Keys that aren't well formed
+// are kept whole.
+func splitKey(key string) (string, []string) {
+	i := strings.IndexByte(key, '[')
+	if i <= 0 || !strings.HasSuffix(key, "]") {
+		return key, nil
+	}
+
+	name := key[:i]
+
+	parts := []string{}
+	rest := key[i:]
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return key, nil
+		}
+
+		parts = append(parts, rest[1:end])
+		rest = rest[end+1:]
+	}
+
+	return name, parts
+}
+
+func assign(m map[string]any, name string, parts []string, value string) {
+	if len(parts) == 0 {
+		m[name] = value
+		return
+	}
+
+	if parts[0] != "" {
+		child, ok := m[name].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			m[name] = child
+		}
+
+		assign(child, parts[0], parts[1:], value)
+		return
+	}
+
+	list, _ := m[name].([]any)
+
+	if len(parts) == 1 {
+		m[name] = append(list, value)
+		return
+	}
+
+	var last map[string]any
+	if len(list) > 0 {
+		last, _ = list[len(list)-1].(map[string]any)
+	}
+
+	if last == nil || has(last, parts[1:]) {
+		last = map[string]any{}
+		list = append(list, last)
+	}
+
+	assign(last, parts[1], parts[2:], value)
+	m[name] = list
+}
+
+// has reports whether the value at parts is already set.
+func has(m map[string]any, parts []string) bool {
+	v, ok := m[parts[0]]
+	if !ok {
+		return false
+	}
+
+	if len(parts) == 1 {
+		return true
+	}
+
+	// lists keep appending to the same entry.
+	if parts[1] == "" {
+		return false
+	}
+
+	child, ok := v.(map[string]any)
+	if !ok {
+		return true
+	}
+
+	return has(child, parts[1:])
+}
@@ -0,0 +1,127 @@
+package params
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecode(t *testing.T) {
+	tt := []struct {
+		name     string
+		query    string
+		expected Params
+	}{
+		{
+			name:     "flat",
+			query:    "name=bob&age=30&empty=",
+			expected: Params{"name": "bob", "age": "30", "empty": ""},
+		},
+		{
+			name:     "escaped",
+			query:    "user%5Bname%5D=bob+smith&q=a%26b",
+			expected: Params{"user": map[string]any{"name": "bob smith"}, "q": "a&b"},
+		},
+		{
+			name:  "nested",
+			query: "user[name]=bob&user[address][city]=Paris&user[address][zip]=75001",
+			expected: Params{"user": map[string]any{
+				"name":    "bob",
+				"address": map[string]any{"city": "Paris", "zip": "75001"},
+			}},
+		},
+		{
+			name:     "list",
+			query:    "tags[]=a&tags[]=b&user[roles][]=admin",
+			expected: Params{"tags": []any{"a", "b"}, "user": map[string]any{"roles": []any{"admin"}}},
+		},
+		{
+			name:  "list of maps",
+			query: "items[][name]=a&items[][qty]=1&items[][name]=b&items[][qty]=2",
+			expected: Params{"items": []any{
+				map[string]any{"name": "a", "qty": "1"},
+				map[string]any{"name": "b", "qty": "2"},
+			}},
+		},
+		{
+			name:  "list of maps with lists",
+			query: "items[][tags][]=a&items[][tags][]=b&items[][name]=x",
+			expected: Params{"items": []any{
+				map[string]any{"tags": []any{"a", "b"}, "name": "x"},
+			}},
+		},
+		{
+			name:  "indexed",
+			query: "items[0][qty]=1&items[1][qty]=2&items[0][name]=a",
+			expected: Params{"items": map[string]any{
+				"0": map[string]any{"qty": "1", "name": "a"},
+				"1": map[string]any{"qty": "2"},
+			}},
+		},
+		{
+			name:     "last value wins",
+			query:    "a=1&a=2",
+			expected: Params{"a": "2"},
+		},
+		{
+			name:     "malformed keys",
+			query:    "a[b=1&[c]=2&d]=3",
+			expected: Params{"a[b": "1", "[c]": "2", "d]": "3"},
+		},
+		{
+			name:     "empty",
+			query:    "",
+			expected: Params{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := Decode(tc.query)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, p)
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	_, err := Decode("a=%zz")
+	assert.EqualError(t, err, `invalid value for a: invalid URL escape "%zz"`)
+}
+
+func TestDecodeForm(t *testing.T) {
+	p, err := DecodeForm("user[name]=bob&_target=user%5Bname%5D")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user", "name"}, p.Target())
+	assert.Equal(t, "bob", p.Map("user").String("name"))
+}
+
+func TestTarget(t *testing.T) {
+	p := Params{"_target": "items[][qty]"}
+	assert.Equal(t, []string{"items", "qty"}, p.Target())
+
+	p.DecodeTarget()
+	assert.Equal(t, []string{"items", "qty"}, p["_target"])
+
+	assert.Nil(t, Params{}.Target())
+}
+
+func TestDecodeBind(t *testing.T) {
+	p, err := Decode("items[1][qty]=2&items[0][qty]=1&tags[]=a&tags[]=b")
+	assert.NoError(t, err)
+
+	var dst struct {
+		Items []struct {
+			Qty int `param:"qty"`
+		} `param:"items"`
+		Tags []string `param:"tags"`
+	}
+
+	assert.NoError(t, Bind(p, &dst))
+	assert.Len(t, dst.Items, 2)
+	assert.Equal(t, 1, dst.Items[0].Qty)
+	assert.Equal(t, 2, dst.Items[1].Qty)
+	assert.Equal(t, []string{"a", "b"}, dst.Tags)
+}
@@ -0,0 +1,513 @@
+package presence
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/sethpollack/go-live-view/channel"
+	"github.com/sethpollack/go-live-view/internal/ref"
+)
+
+const (
+	// StateEvent is the event pushed to clients with the full presence list.
+	StateEvent = "presence_state"
+	// DiffEvent is the event broadcast on a topic when presences join or leave.
+	DiffEvent = "presence_diff"
+
+	// replicationTopic carries presences between nodes. No channel
+	// subscribes to it.
+	replicationTopic = "phx_presence"
+
+	heartbeatEvent = "heartbeat"
+	syncEvent      = "sync"
+	downEvent      = "down"
+
+	defaultHeartbeat = 1500 * time.Millisecond
+	// nodes not heard from for downAfter are dropped with their presences.
+	defaultDownAfter = 30 * time.Second
+)
+
+// Meta is the metadata tracked for a single presence. The phx_ref key is
+// set by the tracker.
+type Meta map[string]any
+
+type Entry struct {
+	Metas []Meta `json:"metas"`
+}
+
+// State is the presence list for a topic keyed by presence key, in the
+// format expected by the Phoenix JS Presence object.
+type State map[string]*Entry
+
+type Diff struct {
+	Joins  State `json:"joins"`
+	Leaves State `json:"leaves"`
+}
+
+type tracked struct {
+	socket string
+	key    string
+	meta   Meta
+}
+
+// remoteNode is what another node has tracked, as last heard from it.
+type remoteNode struct {
+	seen   time.Time
+	synced bool
+	topics map[string]State
+}
+
+// replica is a change to a node's presences sent to the other nodes.
+type replica struct {
+	Node   string           `json:"node"`
+	Target string           `json:"target,omitempty"`
+	Topic  string           `json:"topic,omitempty"`
+	Diff   *Diff            `json:"diff,omitempty"`
+	States map[string]State `json:"states,omitempty"`
+}
+
+type presenceOption func(*Presence)
+
+// Presence tracks presences per topic and broadcasts presence_diff
+// messages through the channel hub. With WithPubSub it also lists the
+// presences tracked by other nodes.
+type Presence struct {
+	node      string
+	pubsub    channel.PubSub
+	heartbeat time.Duration
+	downAfter time.Duration
+
+	mu       sync.Mutex
+	ref      *ref.Ref
+	topics   map[string][]*tracked
+	monitors map[string]bool
+	remote   map[string]*remoteNode
+}
+
+func New(opts ...presenceOption) *Presence {
+	p := &Presence{
+		node:      newNodeID(),
+		heartbeat: defaultHeartbeat,
+		downAfter: defaultDownAfter,
+		ref:       ref.New(0),
+		topics:    make(map[string][]*tracked),
+		monitors:  make(map[string]bool),
+		remote:    make(map[string]*remoteNode),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// WithPubSub shares presences with the nodes listening on ps, which should
+// be the PubSub the hub uses. Presences are only shared while Listen runs.
+func WithPubSub(ps channel.PubSub) presenceOption {
+	return func(p *Presence) {
+		p.pubsub = ps
+	}
+}
+
+func newNodeID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Track adds key with meta to topic for the socket. The presence is removed
+// automatically when the socket's channel closes. Tracking a key the socket
+// already tracks merges meta into its current meta, as an Update.
+func (p *Presence) Track(s channel.Socket, topic, key string, meta map[string]any) error {
+	p.mu.Lock()
+	if old := p.find(s.ID(), topic, key); old != nil {
+		merged := maps.Clone(old.meta)
+		maps.Copy(merged, meta)
+		p.mu.Unlock()
+
+		return p.Update(s, topic, key, merged)
+	}
+
+	t := &tracked{
+		socket: s.ID(),
+		key:    key,
+		meta:   p.newMeta(meta),
+	}
+	p.topics[topic] = append(p.topics[topic], t)
+	monitored := p.monitors[s.ID()]
+	p.monitors[s.ID()] = true
+	p.mu.Unlock()
+
+	if !monitored {
+		id := s.ID()
+		s.OnClose(func() {
+			p.untrackSocket(s, id)
+		})
+	}
+
+	return p.broadcast(s, topic, Diff{
+		Joins:  State{key: {Metas: []Meta{t.meta}}},
+		Leaves: State{},
+	})
+}
+
+// Update replaces the meta tracked for key by the socket.
+func (p *Presence) Update(s channel.Socket, topic, key string, meta map[string]any) error {
+	p.mu.Lock()
+
+	old := p.find(s.ID(), topic, key)
+	if old == nil {
+		p.mu.Unlock()
+		return fmt.Errorf("no presence for %s on %s", key, topic)
+	}
+
+	leave := old.meta
+	old.meta = p.newMeta(meta)
+	join := old.meta
+
+	p.mu.Unlock()
+
+	return p.broadcast(s, topic, Diff{
+		Joins:  State{key: {Metas: []Meta{join}}},
+		Leaves: State{key: {Metas: []Meta{leave}}},
+	})
+}
+
+// Untrack removes key from topic for the socket.
+func (p *Presence) Untrack(s channel.Socket, topic, key string) error {
+	p.mu.Lock()
+	leaves := p.remove(topic, func(t *tracked) bool {
+		return t.socket == s.ID() && t.key == key
+	})
+	p.mu.Unlock()
+
+	if len(leaves) == 0 {
+		return nil
+	}
+
+	return p.broadcast(s, topic, Diff{
+		Joins:  State{},
+		Leaves: leaves,
+	})
+}
+
+// List returns the current presences for topic, including those tracked
+// by other nodes.
+func (p *Presence) List(topic string) State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	state := p.local(topic)
+	for _, n := range p.remote {
+		for key, entry := range n.topics[topic] {
+			for _, meta := range entry.Metas {
+				state.add(key, meta)
+			}
+		}
+	}
+
+	return state
+}
+
+// Listen shares presences with the other nodes on the PubSub set with
+// WithPubSub until ctx is done. Nodes that stop without untracking their
+// presences are dropped from List once they haven't been heard from for a
+// while, but clients aren't sent a diff for them.
+func (p *Presence) Listen(ctx context.Context) {
+	if p.pubsub == nil {
+		<-ctx.Done()
+		return
+	}
+
+	go p.beat(ctx)
+
+	err := p.pubsub.Listen(ctx, p.receive)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Println(err)
+	}
+
+	p.publish(downEvent, replica{Node: p.node})
+}
+
+// beat tells the other nodes this one is up, and drops the nodes that
+// have gone quiet.
+func (p *Presence) beat(ctx context.Context) {
+	ticker := time.NewTicker(p.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		p.publish(heartbeatEvent, replica{Node: p.node})
+
+		p.mu.Lock()
+		for node, n := range p.remote {
+			if time.Since(n.seen) > p.downAfter {
+				delete(p.remote, node)
+			}
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// receive merges a change from another node. A node's full state is
+// requested the first time it's heard from, then kept up to date with
+// its diffs.
+func (p *Presence) receive(msg *channel.Message) {
+	if msg.Topic != replicationTopic {
+		return
+	}
+
+	r := replica{}
+	if err := decode(msg.Payload, &r); err != nil || r.Node == p.node {
+		return
+	}
+
+	switch msg.Event {
+	case syncEvent:
+		if r.Target == p.node {
+			p.publish(StateEvent, replica{Node: p.node, States: p.states()})
+		}
+		return
+	case downEvent:
+		p.mu.Lock()
+		delete(p.remote, r.Node)
+		p.mu.Unlock()
+		return
+	}
+
+	p.mu.Lock()
+	n, ok := p.remote[r.Node]
+	if !ok {
+		n = &remoteNode{topics: make(map[string]State)}
+		p.remote[r.Node] = n
+	}
+	n.seen = time.Now()
+
+	switch msg.Event {
+	case StateEvent:
+		n.synced = true
+		n.topics = r.States
+		if n.topics == nil {
+			n.topics = make(map[string]State)
+		}
+	case DiffEvent:
+		state, ok := n.topics[r.Topic]
+		if !ok {
+			state = State{}
+			n.topics[r.Topic] = state
+		}
+		if r.Diff != nil {
+			state.Apply(*r.Diff)
+		}
+		if len(state) == 0 {
+			delete(n.topics, r.Topic)
+		}
+	}
+
+	synced := n.synced
+	p.mu.Unlock()
+
+	if msg.Event == heartbeatEvent && !synced {
+		p.publish(syncEvent, replica{Node: p.node, Target: r.Node})
+	}
+}
+
+// broadcast sends a diff to the topic's subscribers and to other nodes.
+func (p *Presence) broadcast(s channel.Socket, topic string, d Diff) error {
+	p.publish(DiffEvent, replica{Node: p.node, Topic: topic, Diff: &d})
+
+	return s.PushBroadcast(topic, DiffEvent, d)
+}
+
+func (p *Presence) publish(event string, r replica) {
+	if p.pubsub == nil {
+		return
+	}
+
+	err := p.pubsub.Publish(&channel.Message{
+		Topic:   replicationTopic,
+		Event:   event,
+		Payload: r,
+	})
+	if err != nil {
+		fmt.Println(err)
+	}
+}
+
+// states returns every topic's presences tracked by this node.
+func (p *Presence) states() map[string]State {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	states := make(map[string]State, len(p.topics))
+	for topic := range p.topics {
+		states[topic] = p.local(topic)
+	}
+
+	return states
+}
+
+// local returns topic's presences tracked by this node. p.mu must be held.
+func (p *Presence) local(topic string) State {
+	state := State{}
+	for _, t := range p.topics[topic] {
+		state.add(t.key, t.meta)
+	}
+
+	return state
+}
+
+// find returns the presence tracked for key by the socket. p.mu must be
+// held.
+func (p *Presence) find(socket, topic, key string) *tracked {
+	for _, t := range p.topics[topic] {
+		if t.socket == socket && t.key == key {
+			return t
+		}
+	}
+
+	return nil
+}
+
+func (p *Presence) untrackSocket(s channel.Socket, id string) {
+	p.mu.Lock()
+	delete(p.monitors, id)
+
+	diffs := map[string]State{}
+	for topic := range p.topics {
+		leaves := p.remove(topic, func(t *tracked) bool {
+			return t.socket == id
+		})
+		if len(leaves) > 0 {
+			diffs[topic] = leaves
+		}
+	}
+	p.mu.Unlock()
+
+	for topic, leaves := range diffs {
+		err := p.broadcast(s, topic, Diff{
+			Joins:  State{},
+			Leaves: leaves,
+		})
+		if err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// remove deletes matching presences from topic and returns them. p.mu must
+// be held.
+func (p *Presence) remove(topic string, match func(*tracked) bool) State {
+	removed := State{}
+
+	kept := p.topics[topic][:0]
+	for _, t := range p.topics[topic] {
+		if match(t) {
+			removed.add(t.key, t.meta)
+			continue
+		}
+		kept = append(kept, t)
+	}
+
+	if len(kept) == 0 {
+		delete(p.topics, topic)
+	} else {
+		p.topics[topic] = kept
+	}
+
+	return removed
+}
+
+func (p *Presence) newMeta(meta map[string]any) Meta {
+	m := Meta{}
+	for k, v := range meta {
+		m[k] = v
+	}
+	// refs are unique across nodes, clients remove metas by ref.
+	m["phx_ref"] = p.node + "." + p.ref.NextStringRef()
+
+	return m
+}
+
+// Apply merges a diff into the state, mirroring Presence.syncDiff in the
+// Phoenix JS client. Keys without metas are removed.
+func (s State) Apply(d Diff) {
+	for key, entry := range d.Joins {
+		for _, meta := range entry.Metas {
+			s.add(key, meta)
+		}
+	}
+
+	for key, entry := range d.Leaves {
+		current, ok := s[key]
+		if !ok {
+			continue
+		}
+
+		refs := map[any]bool{}
+		for _, meta := range entry.Metas {
+			refs[meta["phx_ref"]] = true
+		}
+
+		kept := []Meta{}
+		for _, meta := range current.Metas {
+			if !refs[meta["phx_ref"]] {
+				kept = append(kept, meta)
+			}
+		}
+
+		if len(kept) == 0 {
+			delete(s, key)
+		} else {
+			current.Metas = kept
+		}
+	}
+}
+
+func (s State) add(key string, meta Meta) {
+	entry, ok := s[key]
+	if !ok {
+		entry = &Entry{}
+		s[key] = entry
+	}
+
+	entry.Metas = append(entry.Metas, meta)
+}
+
+// DecodeDiff reads a presence diff from a broadcast payload. Payloads are
+// Diff values when delivered in process and decoded JSON when they arrive
+// from another node.
+func DecodeDiff(v any) (Diff, error) {
+	if d, ok := v.(Diff); ok {
+		return d, nil
+	}
+
+	d := Diff{}
+	err := decode(v, &d)
+
+	return d, err
+}
+
+// decode copies a payload into v through JSON, so values shared by every
+// listener in process aren't shared with v.
+func decode(payload, v any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,215 @@
+package presence
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/sethpollack/go-live-view/channel"
+	"github.com/sethpollack/go-live-view/channel/pubsub/memory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var _ channel.Socket = (*testSocket)(nil)
+
+type broadcast struct {
+	topic string
+	diff  Diff
+}
+
+type testSocket struct {
+	channel.Socket
+	id         string
+	closers    []func()
+	broadcasts *[]broadcast
+}
+
+func (s *testSocket) ID() string {
+	return s.id
+}
+
+func (s *testSocket) OnClose(fn func()) {
+	s.closers = append(s.closers, fn)
+}
+
+func (s *testSocket) PushBroadcast(topic string, event string, payload any) error {
+	*s.broadcasts = append(*s.broadcasts, broadcast{topic, payload.(Diff)})
+	return nil
+}
+
+func (s *testSocket) close() {
+	for _, fn := range s.closers {
+		fn()
+	}
+}
+
+func keys(s State) []string {
+	keys := []string{}
+	for k := range s {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestPresence(t *testing.T) {
+	broadcasts := []broadcast{}
+	a := &testSocket{id: "a", broadcasts: &broadcasts}
+	b := &testSocket{id: "b", broadcasts: &broadcasts}
+
+	p := New()
+
+	assert.NoError(t, p.Track(a, "room:1", "alice", map[string]any{"status": "online"}))
+	assert.NoError(t, p.Track(b, "room:1", "bob", map[string]any{"status": "online"}))
+	assert.NoError(t, p.Track(b, "room:2", "bob", nil))
+
+	assert.ElementsMatch(t, []string{"alice", "bob"}, keys(p.List("room:1")))
+	assert.ElementsMatch(t, []string{"bob"}, keys(p.List("room:2")))
+	assert.Equal(t, "online", p.List("room:1")["alice"].Metas[0]["status"])
+	assert.NotEmpty(t, p.List("room:1")["alice"].Metas[0]["phx_ref"])
+
+	assert.Len(t, broadcasts, 3)
+	assert.Equal(t, "room:1", broadcasts[0].topic)
+	assert.Equal(t, []string{"alice"}, keys(broadcasts[0].diff.Joins))
+	assert.Empty(t, broadcasts[0].diff.Leaves)
+
+	assert.NoError(t, p.Update(a, "room:1", "alice", map[string]any{"status": "typing"}))
+	update := broadcasts[3].diff
+	assert.Equal(t, "typing", update.Joins["alice"].Metas[0]["status"])
+	assert.Equal(t, "online", update.Leaves["alice"].Metas[0]["status"])
+	assert.Equal(t, "typing", p.List("room:1")["alice"].Metas[0]["status"])
+
+	assert.NoError(t, p.Untrack(a, "room:1", "alice"))
+	assert.Equal(t, []string{"alice"}, keys(broadcasts[4].diff.Leaves))
+	assert.ElementsMatch(t, []string{"bob"}, keys(p.List("room:1")))
+
+	// closing the channel removes everything tracked by the socket
+	b.close()
+	assert.Empty(t, p.List("room:1"))
+	assert.Empty(t, p.List("room:2"))
+	assert.Len(t, broadcasts, 7)
+	for _, bc := range broadcasts[5:] {
+		assert.Equal(t, []string{"bob"}, keys(bc.diff.Leaves))
+	}
+}
+
+func TestTrackTwice(t *testing.T) {
+	broadcasts := []broadcast{}
+	a := &testSocket{id: "a", broadcasts: &broadcasts}
+
+	p := New()
+
+	assert.NoError(t, p.Track(a, "room:1", "alice", map[string]any{"status": "online"}))
+	assert.NoError(t, p.Track(a, "room:1", "alice", map[string]any{"device": "phone"}))
+
+	metas := p.List("room:1")["alice"].Metas
+	if assert.Len(t, metas, 1) {
+		assert.Equal(t, "online", metas[0]["status"])
+		assert.Equal(t, "phone", metas[0]["device"])
+	}
+
+	// the second track is sent as an update.
+	assert.Len(t, broadcasts, 2)
+	assert.Equal(t, []string{"alice"}, keys(broadcasts[1].diff.Leaves))
+}
+
+func TestNodes(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ps := memory.New()
+
+	newNode := func() *Presence {
+		p := New(WithPubSub(ps))
+		p.heartbeat = 10 * time.Millisecond
+		return p
+	}
+
+	broadcasts := []broadcast{}
+	a := &testSocket{id: "a", broadcasts: &broadcasts}
+	b := &testSocket{id: "b", broadcasts: &broadcasts}
+
+	// presences tracked before a node listens are synced when it does.
+	p1 := newNode()
+	assert.NoError(t, p1.Track(a, "room:1", "alice", nil))
+
+	p2 := newNode()
+	p2ctx, stopP2 := context.WithCancel(ctx)
+
+	go p1.Listen(ctx)
+	go p2.Listen(p2ctx)
+
+	list := func(p *Presence) func() []string {
+		return func() []string { return keys(p.List("room:1")) }
+	}
+
+	assert.Eventually(t, func() bool { return len(list(p2)()) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"alice"}, list(p2)())
+
+	// later changes are merged as they happen.
+	assert.NoError(t, p2.Track(b, "room:1", "bob", nil))
+	assert.NoError(t, p2.Track(b, "room:1", "alice", nil))
+	assert.Eventually(t, func() bool { return len(list(p1)()) == 2 }, time.Second, time.Millisecond)
+	assert.ElementsMatch(t, []string{"alice", "bob"}, list(p1)())
+	assert.Len(t, p1.List("room:1")["alice"].Metas, 2)
+
+	// each node's first ref, still unique across nodes.
+	firstRef := func(p *Presence, key string) any {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.local("room:1")[key].Metas[0]["phx_ref"]
+	}
+	assert.NotEqual(t, firstRef(p1, "alice"), firstRef(p2, "bob"))
+
+	assert.NoError(t, p1.Untrack(a, "room:1", "alice"))
+	assert.Eventually(t, func() bool { return len(p2.List("room:1")["alice"].Metas) == 1 }, time.Second, time.Millisecond)
+
+	// a node that stops is dropped.
+	stopP2()
+	assert.Eventually(t, func() bool { return len(list(p1)()) == 0 }, time.Second, time.Millisecond)
+}
+
+func TestStateApply(t *testing.T) {
+	broadcasts := []broadcast{}
+	a := &testSocket{id: "a", broadcasts: &broadcasts}
+	b := &testSocket{id: "b", broadcasts: &broadcasts}
+
+	p := New()
+	client := State{}
+
+	p.Track(a, "room:1", "alice", nil)
+	p.Track(b, "room:1", "alice", nil)
+	p.Track(b, "room:1", "bob", nil)
+	p.Untrack(b, "room:1", "alice")
+
+	for _, bc := range broadcasts {
+		client.Apply(bc.diff)
+	}
+
+	assert.Equal(t, p.List("room:1"), client)
+	assert.Len(t, client["alice"].Metas, 1)
+}
+
+func TestDecodeDiff(t *testing.T) {
+	diff := Diff{
+		Joins:  State{"alice": {Metas: []Meta{{"phx_ref": "1"}}}},
+		Leaves: State{},
+	}
+
+	decoded, err := DecodeDiff(diff)
+	assert.NoError(t, err)
+	assert.Equal(t, diff, decoded)
+
+	// payloads from other nodes arrive as decoded JSON
+	b, _ := json.Marshal(diff)
+	remote := map[string]any{}
+	json.Unmarshal(b, &remote)
+
+	decoded, err = DecodeDiff(remote)
+	assert.NoError(t, err)
+	assert.Equal(t, diff, decoded)
+
+	b, _ = json.Marshal(diff)
+	assert.JSONEq(t, `{"joins":{"alice":{"metas":[{"phx_ref":"1"}]}},"leaves":{}}`, string(b))
+}
@@ -2,6 +2,7 @@ package rend
 
 import (
 	"reflect"
+	"strconv"
 )
 
 func (oldRoot *Root) Diff(newRoot *Root) *Root {
@@ -13,18 +14,35 @@ func (oldRoot *Root) Diff(newRoot *Root) *Root {
 	root := compareComponents(oldRoot, newRoot)
 	root.Rend = compareRend(oldRoot.Rend, newRoot.Rend)
 
-	if root.Components == nil && root.Rend == nil {
+	// a cleared title is sent as "", so the client shows its default.
+	if title := newRoot.title(); title != oldRoot.title() {
+		root.Title = stringPtr(title)
+	}
+
+	if root.Components == nil && root.Rend == nil && root.Title == nil {
 		return nil
 	}
 
 	return root
 }
 
+func (r *Root) title() string {
+	if r.Title == nil {
+		return ""
+	}
+
+	return *r.Title
+}
+
 func compareComponents(oldRoot, newRoot *Root) *Root {
 	root := &Root{}
 
 	for key, newComponent := range newRoot.Components {
 		oldComponent, exists := oldRoot.Components[key]
+		if oldComponent == newComponent {
+			// live components that weren't re-rendered
+			continue
+		}
 		if !exists {
 			if root.Components == nil {
 				root.Components = make(map[int64]*Rend)
@@ -48,38 +66,108 @@ func compareComponents(oldRoot, newRoot *Root) *Root {
 	return root
 }
 
+// DiffComponents diffs only the components in newRoot, e.g. after a
+// component handled its own event.
+func (oldRoot *Root) DiffComponents(newRoot *Root) *Root {
+	root := compareComponents(oldRoot, newRoot)
+	root.Rend = &Rend{}
+
+	return root
+}
+
 func compareComprehension(oldComp, newComp *Comprehension) *Comprehension {
-	diff := &Comprehension{
-		Stream: newComp.Stream,
+	if oldComp.Fingerprint != newComp.Fingerprint {
+		return newComp.full()
 	}
 
-	if oldComp.Fingerprint != newComp.Fingerprint {
-		return newComp
+	// the client drops stream entries once they're inserted, so they are
+	// always sent in full.
+	if len(newComp.Stream) > 0 {
+		return &Comprehension{
+			Keyed:  keyedEntries(newComp.Dynamics),
+			Stream: newComp.Stream,
+		}
+	}
+
+	keyed := compareKeyed(oldComp, newComp)
+	if keyed == nil {
+		return nil
+	}
+
+	return &Comprehension{
+		Keyed: keyed,
 	}
+}
 
-	if len(oldComp.Dynamics) != len(newComp.Dynamics) {
-		diff.Dynamics = newComp.Dynamics
-		return diff
+// compareKeyed matches entries by key and only sends what changed. Each
+// position holds either the entry's diff, the old position of an unchanged
+// entry that moved, or [old position, diff] for one that moved and changed.
+// Positions that are left out keep their entry.
+func compareKeyed(oldComp, newComp *Comprehension) map[string]any {
+	oldIndex := make(map[any]int, len(oldComp.Dynamics))
+	for i := range oldComp.Dynamics {
+		oldIndex[oldComp.key(i)] = i
 	}
 
-	for i, newDynamicSlice := range newComp.Dynamics {
-		oldDynamicSlice := oldComp.Dynamics[i]
+	keyed := map[string]any{}
 
-		for j, newDynamicElement := range newDynamicSlice {
-			oldDynamicElement := oldDynamicSlice[j]
-			if _, different := elementsEqual(oldDynamicElement, newDynamicElement); different {
-				// if any of the elements are different, force a full render
-				diff.Dynamics = newComp.Dynamics
-				return diff
-			}
+	for i, newEntry := range newComp.Dynamics {
+		pos := strconv.Itoa(i)
+
+		j, exists := oldIndex[newComp.key(i)]
+		if !exists {
+			keyed[pos] = entry(newEntry)
+			continue
+		}
+
+		diff := compareEntry(oldComp.Dynamics[j], newEntry)
+
+		switch {
+		case i == j && diff == nil:
+		case i == j:
+			keyed[pos] = diff
+		case diff == nil:
+			keyed[pos] = j
+		default:
+			keyed[pos] = []any{j, diff}
 		}
 	}
 
-	if len(newComp.Stream) > 0 {
-		return diff
+	if len(keyed) == 0 && len(oldComp.Dynamics) == len(newComp.Dynamics) {
+		return nil
+	}
+
+	keyed["kc"] = len(newComp.Dynamics)
+
+	return keyed
+}
+
+func compareEntry(oldEntry, newEntry []any) map[string]any {
+	if len(oldEntry) != len(newEntry) {
+		return entry(newEntry)
+	}
+
+	var diff map[string]any
+
+	for i, newDynamic := range newEntry {
+		oldDynamic := oldEntry[i]
+
+		val := newDynamic
+		if sameType(oldDynamic, newDynamic) {
+			v, changed := elementsEqual(oldDynamic, newDynamic)
+			if !changed || unchangedRend(v) {
+				continue
+			}
+			val = v
+		}
+
+		if diff == nil {
+			diff = map[string]any{}
+		}
+		diff[strconv.Itoa(i)] = val
 	}
 
-	return nil
+	return diff
 }
 
 func compareRend(oldRend, newRend *Rend) *Rend {
@@ -160,6 +248,11 @@ func elementsEqual(a, b any) (any, bool) {
 	return nil, true
 }
 
+func unchangedRend(v any) bool {
+	r, ok := v.(*Rend)
+	return ok && r != nil && r.Static == nil && len(r.Dynamic) == 0
+}
+
 func sameType(a, b any) bool {
 	switch a.(type) {
 	case int64:
@@ -312,6 +312,76 @@ func TestDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "comprehension keyed moved",
+			a: &Root{
+				Rend: &Rend{
+					Fingerprint: "123",
+					Dynamic: map[string]interface{}{
+						"0": &Comprehension{
+							Fingerprint: "123",
+							Dynamics: [][]any{
+								{"a", "1"},
+								{"b", "2"},
+								{"c", "3"},
+							},
+							Keys: []any{"a", "b", "c"},
+						},
+					},
+				},
+			},
+			b: &Root{
+				Rend: &Rend{
+					Fingerprint: "123",
+					Dynamic: map[string]interface{}{
+						"0": &Comprehension{
+							Fingerprint: "123",
+							Dynamics: [][]any{
+								{"z", "0"},
+								{"a", "1"},
+								{"b", "2"},
+								{"c", "4"},
+							},
+							Keys: []any{"z", "a", "b", "c"},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "comprehension keyed removed",
+			a: &Root{
+				Rend: &Rend{
+					Fingerprint: "123",
+					Dynamic: map[string]interface{}{
+						"0": &Comprehension{
+							Fingerprint: "123",
+							Dynamics: [][]any{
+								{"a"},
+								{"b"},
+								{"c"},
+							},
+							Keys: []any{"a", "b", "c"},
+						},
+					},
+				},
+			},
+			b: &Root{
+				Rend: &Rend{
+					Fingerprint: "123",
+					Dynamic: map[string]interface{}{
+						"0": &Comprehension{
+							Fingerprint: "123",
+							Dynamics: [][]any{
+								{"a"},
+								{"b"},
+							},
+							Keys: []any{"a", "b"},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "comprehension component changed",
 			a: &Root{
@@ -407,6 +477,58 @@ func TestDiff(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "title changed",
+			a: &Root{
+				Title: stringPtr("a"),
+				Rend: &Rend{
+					Static:      []string{"a"},
+					Fingerprint: "123",
+				},
+			},
+			b: &Root{
+				Title: stringPtr("b"),
+				Rend: &Rend{
+					Static:      []string{"a"},
+					Fingerprint: "123",
+				},
+			},
+		},
+		{
+			name: "title cleared",
+			a: &Root{
+				Title: stringPtr("a"),
+				Rend: &Rend{
+					Static:      []string{"a"},
+					Fingerprint: "123",
+				},
+			},
+			b: &Root{
+				Rend: &Rend{
+					Static:      []string{"a"},
+					Fingerprint: "123",
+				},
+			},
+		},
+		{
+			name: "title unchanged",
+			a: &Root{
+				Title: stringPtr("a"),
+				Rend: &Rend{
+					Dynamic:     map[string]interface{}{"0": "a"},
+					Static:      []string{"a", "b"},
+					Fingerprint: "123",
+				},
+			},
+			b: &Root{
+				Title: stringPtr("a"),
+				Rend: &Rend{
+					Dynamic:     map[string]interface{}{"0": "b"},
+					Static:      []string{"a", "b"},
+					Fingerprint: "123",
+				},
+			},
+		},
 	}
 
 	for _, tc := range tt {
@@ -0,0 +1,28 @@
+package rend
+
+import "strings"
+
+var (
+	textEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+	)
+	attrEscaper = strings.NewReplacer(
+		"&", "&amp;",
+		"<", "&lt;",
+		">", "&gt;",
+		`"`, "&#34;",
+		"'", "&#39;",
+	)
+)
+
+// EscapeText escapes s for use as HTML text content.
+func EscapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// EscapeAttr escapes s for use inside a quoted HTML attribute value.
+func EscapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
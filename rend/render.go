@@ -12,8 +12,15 @@ type Node interface {
 }
 
 func RenderString(n Node) string {
+	return RenderStringWith(n, nil)
+}
+
+// RenderStringWith renders n with page level values, such as the csrf
+// token, that nodes can read with Root.Value.
+func RenderStringWith(n Node, values map[string]any) string {
 	b := &strings.Builder{}
 	root := NewRoot()
+	root.values = values
 
 	render(false, root, root.Rend, b, n)
 
@@ -21,7 +28,19 @@ func RenderString(n Node) string {
 }
 
 func RenderTree(n Node) *Root {
-	root := NewRoot()
+	return RenderTreeWith(n, nil)
+}
+
+// RenderTreeWith renders n keeping live components in reg.
+func RenderTreeWith(n Node, reg Registry) *Root {
+	return RenderTreeWithValues(n, reg, nil)
+}
+
+// RenderTreeWithValues is RenderTreeWith with page level values, read with
+// Root.Value.
+func RenderTreeWithValues(n Node, reg Registry, values map[string]any) *Root {
+	root := NewRootWith(reg)
+	root.values = values
 
 	b := &strings.Builder{}
 
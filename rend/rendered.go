@@ -4,16 +4,27 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strconv"
 
 	"github.com/sethpollack/go-live-view/internal/ref"
 )
 
+// Registry keeps live components mounted between renders. Cids it
+// reserves are never handed out to stateless components.
+type Registry interface {
+	Reserved(cid int64) bool
+}
+
 type Root struct {
 	refCID    *ref.Ref
 	streamRef *ref.Ref
+	registry  Registry
+	values    map[string]any
 
 	Components map[int64]*Rend `json:"c,omitempty"`
-	Title      string          `json:"t,omitempty"`
+	Title      *string         `json:"t,omitzero"`
+	Events     [][]any         `json:"e,omitempty"`
+	Reply      any             `json:"r,omitempty"`
 	Rend       *Rend           `json:",inline"`
 }
 
@@ -26,11 +37,64 @@ type Rend struct {
 	Fingerprint string         `json:"f,omitempty"`
 }
 
+// Comprehension is sent keyed: "k" maps each entry's position to its
+// dynamics, and "kc" holds the number of entries.
 type Comprehension struct {
-	Static      []string `json:"s,omitempty"`
-	Dynamics    [][]any  `json:"d,omitempty"`
-	Fingerprint string   `json:"f,omitempty"`
-	Stream      []any    `json:"stream,omitempty"`
+	Static      []string       `json:"s,omitempty"`
+	Keyed       map[string]any `json:"k,omitempty"`
+	Fingerprint string         `json:"f,omitempty"`
+	Stream      []any          `json:"stream,omitempty"`
+
+	// Dynamics and Keys hold the rendered entries and their keys, and are
+	// kept to diff against the next render. Entries without keys are
+	// matched by position.
+	Dynamics [][]any `json:"-"`
+	Keys     []any   `json:"-"`
+}
+
+func NewComprehension(static []string, fingerprint string, dynamics [][]any, keys []any) *Comprehension {
+	return &Comprehension{
+		Static:      static,
+		Fingerprint: fingerprint,
+		Dynamics:    dynamics,
+		Keys:        keys,
+		Keyed:       keyedEntries(dynamics),
+	}
+}
+
+func (c *Comprehension) key(i int) any {
+	if i >= len(c.Keys) {
+		return i
+	}
+	return c.Keys[i]
+}
+
+// full returns c with every entry, for when the client can't merge it.
+func (c *Comprehension) full() *Comprehension {
+	if c.Keyed == nil {
+		c.Keyed = keyedEntries(c.Dynamics)
+	}
+	return c
+}
+
+func keyedEntries(dynamics [][]any) map[string]any {
+	keyed := map[string]any{
+		"kc": len(dynamics),
+	}
+
+	for i, d := range dynamics {
+		keyed[strconv.Itoa(i)] = entry(d)
+	}
+
+	return keyed
+}
+
+func entry(d []any) map[string]any {
+	e := map[string]any{}
+	for i, v := range d {
+		e[strconv.Itoa(i)] = v
+	}
+	return e
 }
 
 func NewRoot() *Root {
@@ -41,22 +105,59 @@ func NewRoot() *Root {
 	}
 }
 
+// NewRootWith returns a root whose components are tracked by reg.
+func NewRootWith(reg Registry) *Root {
+	root := NewRoot()
+	root.registry = reg
+	return root
+}
+
+func (r *Root) Registry() Registry {
+	return r.registry
+}
+
+// Value returns a page level value passed to RenderStringWith or
+// RenderTreeWithValues.
+func (r *Root) Value(key string) any {
+	return r.values[key]
+}
+
 func (r *Root) NextStreamID() int64 {
 	return r.streamRef.NextRef()
 }
 
-func (rend *Rend) AddComponent(r *Root, c *Rend) {
-	id := r.refCID.NextRef()
-
-	rend.AddDynamic(id)
+// NextCID returns the next cid that isn't used in this render or reserved
+// by the registry.
+func (r *Root) NextCID() int64 {
+	for {
+		id := r.refCID.NextRef()
+		if _, ok := r.Components[id]; ok {
+			continue
+		}
+		if r.registry != nil && r.registry.Reserved(id) {
+			continue
+		}
+		return id
+	}
+}
 
+// SetComponent stores c under cid without referencing it from a dynamic.
+func (r *Root) SetComponent(cid int64, c *Rend) {
 	if r.Components == nil {
 		r.Components = map[int64]*Rend{}
 	}
 
 	c.Root = boolPtr(true)
 
-	r.Components[id] = c
+	r.Components[cid] = c
+}
+
+func (rend *Rend) AddComponent(r *Root, c *Rend) {
+	id := r.NextCID()
+
+	rend.AddDynamic(id)
+
+	r.SetComponent(id, c)
 }
 
 func (r *Rend) NextID() int64 {
@@ -91,3 +192,7 @@ func fingerPrint(s []string) string {
 func boolPtr(b bool) *bool {
 	return &b
 }
+
+func stringPtr(s string) *string {
+	return &s
+}
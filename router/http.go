@@ -0,0 +1,200 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// WithMiddleware wraps the route's handlers, and the view's HTTP render,
+// in mw. The first middleware is the outermost. Middleware set on a group
+// applies to all of its routes.
+//
+// Middleware doesn't run when the socket joins. Sockets can only join
+// routes in the live session of the page they were rendered with, but live
+// navigation within a live session skips the middleware of the route
+// navigated to. Put authorization in the live session's OnMount hooks
+// (see WithSession), which run for both.
+func WithMiddleware(mw ...func(http.Handler) http.Handler) routeOption {
+	return func(r *route) {
+		r.middleware = append(r.middleware, mw...)
+	}
+}
+
+// WithMethodNotAllowed sets the handler for requests to a path that doesn't
+// handle their method. By default a 405 is written with an Allow header.
+func WithMethodNotAllowed(h http.Handler) routerOption {
+	return func(r *router) {
+		r.methodNotAllowed = h
+	}
+}
+
+func (r *router) Method(method, path string, h http.Handler, opts ...routeOption) *route {
+	route := &route{
+		path:   path,
+		router: r,
+	}
+
+	for _, opt := range opts {
+		opt(route)
+	}
+
+	return r.addHandler(method, path, h, route)
+}
+
+func (r *router) Get(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return r.Method(http.MethodGet, path, h, opts...)
+}
+
+func (r *router) Post(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return r.Method(http.MethodPost, path, h, opts...)
+}
+
+func (r *router) Put(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return r.Method(http.MethodPut, path, h, opts...)
+}
+
+func (r *router) Patch(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return r.Method(http.MethodPatch, path, h, opts...)
+}
+
+func (r *router) Delete(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return r.Method(http.MethodDelete, path, h, opts...)
+}
+
+func (rg *routeGroup) Method(method, path string, h http.Handler, opts ...routeOption) *route {
+	fullPath := rg.combinePaths(rg.path, path)
+
+	route := &route{
+		path:   fullPath,
+		router: rg.router,
+		parent: rg.parent,
+	}
+
+	rg.apply(route, opts)
+
+	return rg.router.addHandler(method, fullPath, h, route)
+}
+
+func (rg *routeGroup) Get(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return rg.Method(http.MethodGet, path, h, opts...)
+}
+
+func (rg *routeGroup) Post(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return rg.Method(http.MethodPost, path, h, opts...)
+}
+
+func (rg *routeGroup) Put(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return rg.Method(http.MethodPut, path, h, opts...)
+}
+
+func (rg *routeGroup) Patch(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return rg.Method(http.MethodPatch, path, h, opts...)
+}
+
+func (rg *routeGroup) Delete(path string, h http.HandlerFunc, opts ...routeOption) *route {
+	return rg.Method(http.MethodDelete, path, h, opts...)
+}
+
+// addHandler adds h for method under path. A path registered before keeps
+// its route, so only route's middleware is used.
+func (r *router) addHandler(method, path string, h http.Handler, route *route) *route {
+	method = strings.ToUpper(method)
+
+	h = wrap(h, route.middleware)
+
+	existing, ok := r.byPath[path]
+	if !ok {
+		route.handlers = map[string]http.Handler{method: h}
+		r.add(path, route)
+		return route
+	}
+
+	if _, ok := existing.handlers[method]; ok {
+		panic(fmt.Sprintf("router: route %s already handles %s", path, method))
+	}
+
+	if existing.view != nil && (method == http.MethodGet || method == http.MethodHead) {
+		panic(fmt.Sprintf("router: route %s already handles %s with a view", path, method))
+	}
+
+	if existing.handlers == nil {
+		existing.handlers = make(map[string]http.Handler)
+	}
+	existing.handlers[method] = h
+
+	return existing
+}
+
+// Dispatch serves req with the handler registered for its path and method.
+// Views are rendered by live, and paths without a route are left to live so
+// 404s are rendered like any other page.
+func (r *router) Dispatch(w http.ResponseWriter, req *http.Request, live http.Handler) {
+	node, pathParams, exact := r.root.Lookup(req.URL.Path)
+
+	route := node.GetRoute()
+	if !exact || route == nil || (route.view == nil && len(route.handlers) == 0) {
+		live.ServeHTTP(w, req)
+		return
+	}
+
+	if h := route.handler(req.Method); h != nil {
+		for key, value := range pathParams {
+			req.SetPathValue(key, fmt.Sprint(value))
+		}
+
+		h.ServeHTTP(w, req)
+		return
+	}
+
+	if route.view != nil && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		wrap(live, route.middleware).ServeHTTP(w, req)
+		return
+	}
+
+	w.Header().Set("Allow", strings.Join(route.allowed(), ", "))
+
+	if r.methodNotAllowed != nil {
+		r.methodNotAllowed.ServeHTTP(w, req)
+		return
+	}
+
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
+
+// handler returns the handler for method. HEAD falls back to GET.
+func (r *route) handler(method string) http.Handler {
+	if h, ok := r.handlers[method]; ok {
+		return h
+	}
+
+	if method == http.MethodHead {
+		return r.handlers[http.MethodGet]
+	}
+
+	return nil
+}
+
+func (r *route) allowed() []string {
+	methods := make([]string, 0, len(r.handlers)+2)
+	for method := range r.handlers {
+		methods = append(methods, method)
+	}
+
+	_, get := r.handlers[http.MethodGet]
+	if r.view != nil || get {
+		methods = append(methods, http.MethodGet, http.MethodHead)
+	}
+
+	// GET and HEAD are listed twice when they're also handlers.
+	slices.Sort(methods)
+	return slices.Compact(methods)
+}
+
+func wrap(h http.Handler, middleware []func(http.Handler) http.Handler) http.Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
@@ -0,0 +1,281 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sethpollack/go-live-view/rend"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tag(name string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("X-Middleware", name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func text(s string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, s)
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	r := NewRouter(testLayout)
+
+	r.Handle("/", &testLive{name: "home"})
+	r.Post("/", text("posted home"))
+
+	api := r.Group("/api", nil, WithMiddleware(tag("api")))
+	api.Get("/users/:id<int>", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "user %s", r.PathValue("id"))
+	}, WithMiddleware(tag("user")))
+	api.Delete("/users/:id<int>", text("deleted"))
+
+	admin := r.Group("/admin", &testLive{name: "admin"}, WithMiddleware(tag("admin")))
+	admin.Handle("/settings", &testLive{name: "settings"})
+
+	live := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "live %s", r.URL.Path)
+	})
+
+	tt := []struct {
+		name       string
+		method     string
+		path       string
+		code       int
+		body       string
+		middleware []string
+		allow      string
+	}{
+		{
+			name:   "view",
+			method: http.MethodGet,
+			path:   "/",
+			code:   http.StatusOK,
+			body:   "live /",
+		},
+		{
+			name:   "handler on a view's path",
+			method: http.MethodPost,
+			path:   "/",
+			code:   http.StatusOK,
+			body:   "posted home",
+		},
+		{
+			name:       "handler with path values",
+			method:     http.MethodGet,
+			path:       "/api/users/12",
+			code:       http.StatusOK,
+			body:       "user 12",
+			middleware: []string{"api", "user"},
+		},
+		{
+			name:       "head falls back to get",
+			method:     http.MethodHead,
+			path:       "/api/users/12",
+			code:       http.StatusOK,
+			body:       "user 12",
+			middleware: []string{"api", "user"},
+		},
+		{
+			name:       "group middleware",
+			method:     http.MethodDelete,
+			path:       "/api/users/12",
+			code:       http.StatusOK,
+			body:       "deleted",
+			middleware: []string{"api"},
+		},
+		{
+			name:       "view middleware",
+			method:     http.MethodGet,
+			path:       "/admin/settings",
+			code:       http.StatusOK,
+			body:       "live /admin/settings",
+			middleware: []string{"admin"},
+		},
+		{
+			name:   "method not allowed",
+			method: http.MethodPut,
+			path:   "/api/users/12",
+			code:   http.StatusMethodNotAllowed,
+			body:   "Method Not Allowed\n",
+			allow:  "DELETE, GET, HEAD",
+		},
+		{
+			name:   "method not allowed on a view",
+			method: http.MethodDelete,
+			path:   "/",
+			code:   http.StatusMethodNotAllowed,
+			body:   "Method Not Allowed\n",
+			allow:  "GET, HEAD, POST",
+		},
+		{
+			name:   "group without a view",
+			method: http.MethodGet,
+			path:   "/api",
+			code:   http.StatusOK,
+			body:   "live /api",
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			path:   "/api/users/abc",
+			code:   http.StatusOK,
+			body:   "live /api/users/abc",
+		},
+		{
+			name:   "not found handler path",
+			method: http.MethodPost,
+			path:   "/api/users/12/posts",
+			code:   http.StatusOK,
+			body:   "live /api/users/12/posts",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			r.Dispatch(w, httptest.NewRequest(tc.method, tc.path, nil), live)
+
+			assert.Equal(t, tc.code, w.Code)
+			assert.Equal(t, tc.body, w.Body.String())
+			assert.Equal(t, tc.middleware, w.Header().Values("X-Middleware"))
+			assert.Equal(t, tc.allow, w.Header().Get("Allow"))
+		})
+	}
+}
+
+func TestDispatchMethodNotAllowed(t *testing.T) {
+	r := NewRouter(testLayout, WithMethodNotAllowed(text("nope")))
+	r.Get("/foo", text("foo"))
+
+	w := httptest.NewRecorder()
+	r.Dispatch(w, httptest.NewRequest(http.MethodPost, "/foo", nil), nil)
+
+	assert.Equal(t, "nope", w.Body.String())
+	assert.Equal(t, "GET, HEAD", w.Header().Get("Allow"))
+}
+
+func TestHandlerOnlyRouteNotFound(t *testing.T) {
+	r := NewRouter(testLayout)
+	r.Get("/foo", text("foo"))
+
+	_, err := r.GetRoute("/foo")
+	assert.Error(t, err)
+}
+
+func TestHandlerConflicts(t *testing.T) {
+	tt := []struct {
+		name     string
+		register func(r *router)
+		err      string
+	}{
+		{
+			name: "duplicate method",
+			register: func(r *router) {
+				r.Post("/foo", text("a"))
+				r.Post("/foo", text("b"))
+			},
+			err: "router: route /foo already handles POST",
+		},
+		{
+			name: "get on a view",
+			register: func(r *router) {
+				r.Handle("/foo", &testLive{})
+				r.Get("/foo", text("a"))
+			},
+			err: "router: route /foo already handles GET with a view",
+		},
+		{
+			name: "view on a get",
+			register: func(r *router) {
+				r.Get("/foo", text("a"))
+				r.Handle("/foo", &testLive{})
+			},
+			err: "router: route /foo already handles GET",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.PanicsWithValue(t, tc.err, func() {
+				tc.register(NewRouter(testLayout))
+			})
+		})
+	}
+}
+
+func TestViewInGroupWithoutView(t *testing.T) {
+	r := NewRouter(testLayout)
+	r.Group("/api", nil).Handle("/docs", &testLive{name: "docs"})
+
+	route, err := r.GetRoute("/api/docs")
+	assert.NoError(t, err)
+
+	node, err := route.GetView().Render(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "<div>docs</div>", rend.RenderString(node))
+}
+
+func TestViewAfterHandler(t *testing.T) {
+	r := NewRouter(testLayout)
+	r.Post("/foo", text("posted"))
+	r.Handle("/foo", &testLive{name: "foo"})
+
+	route, err := r.GetRoute("/foo")
+	assert.NoError(t, err)
+
+	node, err := route.GetView().Render(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "<div>foo</div>", rend.RenderString(node))
+
+	w := httptest.NewRecorder()
+	r.Dispatch(w, httptest.NewRequest(http.MethodPost, "/foo", nil), nil)
+	assert.Equal(t, "posted", w.Body.String())
+}
+
+func TestDispatchExtraSegments(t *testing.T) {
+	forbid := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+		})
+	}
+
+	r := NewRouter(testLayout)
+	r.Group("/admin", nil, WithMiddleware(forbid)).Handle("/users", &testLive{name: "users"})
+
+	live := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, err := r.GetRoute(req.URL.Path)
+		if err != nil {
+			http.NotFound(w, req)
+			return
+		}
+		fmt.Fprint(w, "rendered")
+	})
+
+	tt := []struct {
+		path string
+		code int
+	}{
+		{path: "/admin/users", code: http.StatusForbidden},
+		{path: "/admin/users/x", code: http.StatusNotFound},
+		{path: "/admin/users/bogus/more", code: http.StatusNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.Dispatch(w, httptest.NewRequest(http.MethodGet, tc.path, nil), live)
+
+			assert.Equal(t, tc.code, w.Code)
+		})
+	}
+}
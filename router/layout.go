@@ -0,0 +1,49 @@
+package router
+
+import (
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/rend"
+)
+
+// rootLayout is the static layout around the page. Routes share a root
+// layout when they point to the same one.
+type rootLayout struct {
+	render func(...rend.Node) rend.Node
+}
+
+// WithRootLayout renders the route, and the routes of a group, in layout
+// instead of the router's. The root layout is only rendered over HTTP, so
+// navigating to a route with another root layout requires a full page load.
+func WithRootLayout(layout func(...rend.Node) rend.Node) routeOption {
+	root := &rootLayout{
+		render: layout,
+	}
+
+	return func(r *route) {
+		r.rootLayout = root
+	}
+}
+
+// WithLayout wraps the route's view in layout, over HTTP and on the socket.
+// The layouts of a group wrap the layouts of its routes.
+func WithLayout(layout func(rend.Node) rend.Node) routeOption {
+	return func(r *route) {
+		r.layout = layout
+	}
+}
+
+func (m *match) GetLayout() func(...rend.Node) rend.Node {
+	return findRootLayout(m.route).render
+}
+
+func findRootLayout(route *route) *rootLayout {
+	if route.rootLayout != nil {
+		return route.rootLayout
+	}
+
+	return route.router.layout
+}
+
+func (r *router) sameRootLayout(from lv.Route, to lv.Route) bool {
+	return findRootLayout(from.(*match).route) == findRootLayout(to.(*match).route)
+}
@@ -0,0 +1,349 @@
+package router
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/sethpollack/go-live-view/internal/tree"
+	lv "github.com/sethpollack/go-live-view/liveview"
+	"github.com/sethpollack/go-live-view/params"
+)
+
+// WithName names a route so its path can be built with Path. Names aren't
+// inherited by the routes of a group.
+func WithName(name string) routeOption {
+	return func(r *route) {
+		r.name = name
+	}
+}
+
+// Path builds the path for the route named name. Params are filled into
+// the route's :params, and the rest are added as the query string.
+func (r *router) Path(name string, p params.Params) (string, error) {
+	route, ok := r.names[name]
+	if !ok {
+		return "", fmt.Errorf("route %s not found", name)
+	}
+
+	return buildPath(route.path, p)
+}
+
+func (r *router) register(route *route) {
+	if route.name == "" {
+		return
+	}
+
+	if _, ok := r.names[route.name]; ok {
+		panic(fmt.Sprintf("router: route name %s already used", route.name))
+	}
+
+	r.names[route.name] = route
+}
+
+func buildPath(pattern string, p params.Params) (string, error) {
+	used := map[string]bool{}
+
+	segments := strings.Split(pattern, "/")
+	for i, s := range segments {
+		seg, err := tree.ParseSegment(s)
+		if err != nil {
+			return "", err
+		}
+
+		if seg.Kind == tree.Static {
+			continue
+		}
+
+		value, ok := p[seg.Key]
+		v := fmt.Sprint(value)
+		used[seg.Key] = true
+
+		switch {
+		case seg.Kind == tree.CatchAll:
+			// catch-alls also match nothing.
+			if !ok {
+				v = ""
+			}
+			segments[i] = escapeRest(v)
+		case !ok || v == "":
+			return "", fmt.Errorf("path %s: missing param %s", pattern, seg.Key)
+		case !seg.Match(v):
+			return "", fmt.Errorf("path %s: param %s doesn't match %s", pattern, seg.Key, s)
+		default:
+			segments[i] = url.PathEscape(v)
+		}
+	}
+
+	path := strings.Join(segments, "/")
+
+	query := url.Values{}
+	for key, value := range p {
+		if used[key] {
+			continue
+		}
+
+		switch v := value.(type) {
+		case []string:
+			query[key] = v
+		case []any:
+			for _, e := range v {
+				query.Add(key, fmt.Sprint(e))
+			}
+		default:
+			query.Set(key, fmt.Sprint(v))
+		}
+	}
+
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	return path, nil
+}
+
+// escapeRest escapes a catch-all value, keeping its slashes.
+func escapeRest(s string) string {
+	return strings.ReplaceAll(url.PathEscape(s), "%2F", "/")
+}
+
+// GeneratePaths writes a Go file for package pkg with a function for each
+// of the router's named routes, taking the route's params as arguments:
+//
+//	//go:generate go run . -paths paths/paths.go
+func GeneratePaths(w io.Writer, pkg string, rt lv.Router) error {
+	r, ok := rt.(*router)
+	if !ok {
+		return fmt.Errorf("can't generate paths for %T", rt)
+	}
+
+	names := make([]string, 0, len(r.names))
+	for name := range r.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	funcs := []pathFunc{}
+	patterns := []pathPattern{}
+	imports := map[string]bool{}
+	for _, name := range names {
+		f := newPathFunc(name, r.names[name].path)
+		funcs = append(funcs, f)
+		for _, c := range f.Checks {
+			patterns = append(patterns, c.pathPattern)
+		}
+		for imp := range f.imports {
+			imports[imp] = true
+		}
+	}
+
+	sorted := make([]string, 0, len(imports))
+	for imp := range imports {
+		sorted = append(sorted, imp)
+	}
+	sort.Strings(sorted)
+
+	var b bytes.Buffer
+	err := pathsTmpl.Execute(&b, map[string]any{
+		"Package":  pkg,
+		"Funcs":    funcs,
+		"Patterns": patterns,
+		"Imports":  sorted,
+	})
+	if err != nil {
+		return err
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(src)
+	return err
+}
+
+type pathFunc struct {
+	Name    string
+	Route   string
+	Pattern string
+	Args    []string
+	Expr    string
+	// Checks are the params whose type doesn't guarantee they match their
+	// segment, making the function return an error.
+	Checks []pathCheck
+
+	imports map[string]bool
+}
+
+type pathPattern struct {
+	Var     string
+	Pattern string
+}
+
+type pathCheck struct {
+	pathPattern
+	Value string
+	Err   string
+}
+
+func newPathFunc(name, pattern string) pathFunc {
+	f := pathFunc{
+		Name:    identifier(name, true) + "Path",
+		Route:   name,
+		Pattern: pattern,
+		imports: map[string]bool{},
+	}
+
+	parts := []string{}
+	literal := ""
+
+	for i, s := range strings.Split(pattern, "/") {
+		if i > 0 {
+			literal += "/"
+		}
+
+		// patterns were already parsed when the route was added.
+		seg, _ := tree.ParseSegment(s)
+		if seg.Kind == tree.Static {
+			literal += s
+			continue
+		}
+
+		arg := identifier(seg.Key, false)
+		if seg.Key == "*" {
+			arg = "rest"
+		}
+
+		var expr string
+		switch {
+		case seg.Kind == tree.CatchAll:
+			f.Args = append(f.Args, arg+" string")
+			expr = fmt.Sprintf(`strings.ReplaceAll(url.PathEscape(%s), "%%2F", "/")`, arg)
+			f.imports["net/url"] = true
+			f.imports["strings"] = true
+		case seg.Type == "int":
+			f.Args = append(f.Args, arg+" int")
+			expr = fmt.Sprintf("strconv.Itoa(%s)", arg)
+			f.imports["strconv"] = true
+		case seg.Type == "bool":
+			f.Args = append(f.Args, arg+" bool")
+			expr = fmt.Sprintf("strconv.FormatBool(%s)", arg)
+			f.imports["strconv"] = true
+		case seg.Type == "float":
+			// NaN and infinities don't match.
+			f.Args = append(f.Args, arg+" float64")
+			expr = fmt.Sprintf("strconv.FormatFloat(%s, 'f', -1, 64)", arg)
+			f.check(name, pattern, s, seg, expr)
+			f.imports["strconv"] = true
+		case seg.Kind == tree.Constrained:
+			f.Args = append(f.Args, arg+" string")
+			f.check(name, pattern, s, seg, arg)
+			expr = fmt.Sprintf("url.PathEscape(%s)", arg)
+			f.imports["net/url"] = true
+		default:
+			f.Args = append(f.Args, arg+" string")
+			expr = fmt.Sprintf("url.PathEscape(%s)", arg)
+			f.imports["net/url"] = true
+		}
+
+		parts = append(parts, fmt.Sprintf("%q", literal), expr)
+		literal = ""
+	}
+
+	if literal != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", literal))
+	}
+
+	f.Expr = strings.Join(parts, " + ")
+
+	return f
+}
+
+// check makes the function return an error when value doesn't match seg,
+// the same one Path returns.
+func (f *pathFunc) check(name, pattern, s string, seg tree.Segment, value string) {
+	f.Checks = append(f.Checks, pathCheck{
+		pathPattern: pathPattern{
+			Var:     identifier(name, false) + identifier(seg.Key, true) + "Pattern",
+			Pattern: seg.Pattern(),
+		},
+		Value: value,
+		Err:   fmt.Sprintf("path %s: param %s doesn't match %s", pattern, seg.Key, s),
+	})
+
+	f.imports["errors"] = true
+	f.imports["regexp"] = true
+}
+
+// identifier turns names like "nested_edit" into "NestedEdit", or
+// "nestedEdit" when exported is false.
+func identifier(name string, exported bool) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for i, word := range words {
+		runes := []rune(word)
+		if i > 0 || exported {
+			runes[0] = unicode.ToUpper(runes[0])
+		} else {
+			runes[0] = unicode.ToLower(runes[0])
+		}
+		b.WriteString(string(runes))
+	}
+
+	id := b.String()
+	if id == "" || unicode.IsDigit([]rune(id)[0]) {
+		id = "p" + id
+	}
+	if token.IsKeyword(id) {
+		id += "Param"
+	}
+
+	return id
+}
+
+var pathsTmpl = template.Must(template.New("paths").Parse(`// Code generated by router.GeneratePaths. DO NOT EDIT.
+
+package {{ .Package }}
+
+{{ if eq (len .Imports) 1 }}
+import "{{ index .Imports 0 }}"
+{{ else if .Imports }}
+import (
+{{ range .Imports }}	"{{ . }}"
+{{ end }})
+{{ end }}
+
+{{ if .Patterns }}
+var (
+{{ range .Patterns }}	{{ .Var }} = regexp.MustCompile({{ printf "%q" .Pattern }})
+{{ end }})
+{{ end }}
+
+{{ range .Funcs }}{{ if .Checks }}
+// {{ .Name }} returns the path for {{ .Route }}, {{ .Pattern }}. It errors
+// when a param doesn't match its segment.
+func {{ .Name }}({{ range $i, $a := .Args }}{{ if $i }}, {{ end }}{{ $a }}{{ end }}) (string, error) {
+{{ range .Checks }}	if !{{ .Var }}.MatchString({{ .Value }}) {
+		return "", errors.New({{ printf "%q" .Err }})
+	}
+{{ end }}
+	return {{ .Expr }}, nil
+}
+{{ else }}
+// {{ .Name }} returns the path for {{ .Route }}, {{ .Pattern }}.
+func {{ .Name }}({{ range $i, $a := .Args }}{{ if $i }}, {{ end }}{{ $a }}{{ end }}) string {
+	return {{ .Expr }}
+}
+{{ end }}{{ end }}
+`))
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/url"
 	"path"
@@ -12,7 +14,7 @@ import (
 	"github.com/sethpollack/go-live-view/rend"
 )
 
-var _ lv.Route = (*route)(nil)
+var _ lv.Route = (*match)(nil)
 var _ lv.Router = (*router)(nil)
 
 type routerOption func(*router)
@@ -21,20 +23,40 @@ type routeOption func(*route)
 type router struct {
 	root     *tree.Node[*route]
 	mounted  map[*route]bool
-	layout   func(...rend.Node) rend.Node
+	names    map[string]*route
+	byPath   map[string]*route
+	layout   *rootLayout
 	notFound *route
+
+	methodNotAllowed http.Handler
 }
 type route struct {
+	name    string
 	path    string
 	view    lv.View
 	params  params.Params
-	session string
+	session *liveSession
+
+	layout     func(rend.Node) rend.Node
+	rootLayout *rootLayout
 
 	parent *route
 	router *router
 
 	httpMounts []func(http.ResponseWriter, *http.Request, params.Params) error
 	mounts     []func(lv.Socket, params.Params) error
+
+	// handlers are plain http handlers by method, already wrapped in their
+	// middleware. middleware wraps the view's HTTP render.
+	handlers   map[string]http.Handler
+	middleware []func(http.Handler) http.Handler
+}
+
+// liveSession is a named set of routes sharing on_mount hooks. Navigating
+// between live sessions requires a full page load.
+type liveSession struct {
+	name    string
+	onMount []lv.OnMount
 }
 
 type routeGroup struct {
@@ -52,10 +74,14 @@ func NewRouter(
 	r := &router{
 		root:    tree.New[*route](),
 		mounted: make(map[*route]bool),
-		layout:  layout,
-		notFound: &route{
-			view: &notFound{},
-		},
+		names:   make(map[string]*route),
+		byPath:  make(map[string]*route),
+		layout:  &rootLayout{render: layout},
+	}
+
+	r.notFound = &route{
+		view:   &notFound{},
+		router: r,
 	}
 
 	for _, opt := range opts {
@@ -68,7 +94,8 @@ func NewRouter(
 func WithNotFound(view lv.View) routerOption {
 	return func(r *router) {
 		r.notFound = &route{
-			view: view,
+			view:   view,
+			router: r,
 		}
 	}
 }
@@ -86,7 +113,15 @@ func WithParams(params params.Params) routeOption {
 	}
 }
 
-func WithSession(session string) routeOption {
+// WithSession puts the route, and the routes of a group, in the live
+// session name. hooks run before any view in the session mounts, over HTTP
+// and again on the socket.
+func WithSession(name string, hooks ...lv.OnMount) routeOption {
+	session := &liveSession{
+		name:    name,
+		onMount: hooks,
+	}
+
 	return func(r *route) {
 		r.session = session
 	}
@@ -105,7 +140,7 @@ func WithMount(middleware func(lv.Socket, params.Params) error) routeOption {
 }
 
 func (r *router) GetLayout() func(...rend.Node) rend.Node {
-	return r.layout
+	return r.layout.render
 }
 
 func (r *router) Group(path string, view lv.View, opts ...routeOption) *routeGroup {
@@ -119,7 +154,7 @@ func (r *router) Group(path string, view lv.View, opts ...routeOption) *routeGro
 		opt(route)
 	}
 
-	r.root.AddRoute(path, route)
+	r.add(path, route)
 
 	return &routeGroup{
 		router:  r,
@@ -140,39 +175,62 @@ func (r *router) Handle(path string, view lv.View, opts ...routeOption) *route {
 		opt(route)
 	}
 
-	r.root.AddRoute(path, route)
+	r.add(path, route)
 	return route
 }
 
-func (r *router) GetRoute(path string) (lv.Route, error) {
-	node, params, err := r.findNode(path)
+// add registers route under path. Conflicting paths and names are
+// programming errors, so they panic like http.ServeMux does.
+func (r *router) add(path string, route *route) {
+	if existing, ok := r.byPath[path]; ok && existing.view == nil {
+		// a view joining the http handlers of its path.
+		if _, ok := existing.handlers[http.MethodGet]; ok {
+			panic(fmt.Sprintf("router: route %s already handles GET", path))
+		}
+
+		route.handlers = existing.handlers
+		*existing = *route
+		r.register(existing)
+
+		return
+	}
+
+	err := r.root.AddRoute(path, route)
 	if err != nil {
-		return nil, err
+		panic(fmt.Sprintf("router: %s", err))
 	}
 
-	route := node.GetRoute()
+	r.byPath[path] = route
+	r.register(route)
+}
 
-	if route == nil {
-		return r.notFound, lv.NotFoundError
+// GetRoute returns the route matching path along with its params. Each
+// call returns a new match, so lookups never share params.
+func (r *router) GetRoute(path string) (lv.Route, error) {
+	node, pathParams, err := r.findNode(path)
+	if errors.Is(err, tree.ErrNotFound) {
+		return &match{route: r.notFound}, lv.NotFoundError
 	}
-
-	if route.params == nil {
-		route.params = make(map[string]any)
+	if err != nil {
+		return nil, err
 	}
 
-	for key, value := range params {
-		route.params[key] = value
+	route := node.GetRoute()
+
+	// routes with only http handlers can't be rendered.
+	if route == nil || route.view == nil {
+		return &match{route: r.notFound}, lv.NotFoundError
 	}
 
-	return route, nil
+	return newMatch(route, pathParams), nil
 }
 
 func (r *router) Routable(from lv.Route, to lv.Route) bool {
-	return r.sameSession(from, to)
+	return r.sameSession(from, to) && r.sameRootLayout(from, to)
 }
 
 func (r *router) sameSession(from lv.Route, to lv.Route) bool {
-	return findSession(from.(*route)) == findSession(to.(*route))
+	return findSession(from.(*match).route).name == findSession(to.(*match).route).name
 }
 
 func (r *router) findNode(path string) (*tree.Node[*route], map[string]any, error) {
@@ -193,20 +251,43 @@ func (r *router) findNode(path string) (*tree.Node[*route], map[string]any, erro
 	return node, params, nil
 }
 
-func (r *route) GetView() lv.View {
-	return newWrapper(r)
+// match is a route found by GetRoute, along with the params for that
+// lookup.
+type match struct {
+	route  *route
+	params params.Params
+}
+
+func newMatch(r *route, pathParams params.Params) *match {
+	return &match{
+		route:  r,
+		params: params.Merge(r.params, pathParams),
+	}
+}
+
+func (m *match) GetView() lv.View {
+	return newWrapper(m.route)
+}
+
+// GetParams returns a copy of the match's params.
+func (m *match) GetParams() params.Params {
+	if m.params == nil {
+		return nil
+	}
+
+	return params.Merge(m.params)
 }
 
-func (r *route) GetParams() params.Params {
-	return r.params
+func (m *match) GetHttpMounts() []func(http.ResponseWriter, *http.Request, params.Params) error {
+	return m.route.httpMounts
 }
 
-func (r *route) GetHttpMounts() []func(http.ResponseWriter, *http.Request, params.Params) error {
-	return r.httpMounts
+func (m *match) GetMounts() []func(lv.Socket, params.Params) error {
+	return m.route.mounts
 }
 
-func (r *route) GetMounts() []func(lv.Socket, params.Params) error {
-	return r.mounts
+func (m *match) GetOnMount() []lv.OnMount {
+	return findSession(m.route).onMount
 }
 
 func (rg *routeGroup) Group(path string, view lv.View, opts ...routeOption) *routeGroup {
@@ -219,12 +300,9 @@ func (rg *routeGroup) Group(path string, view lv.View, opts ...routeOption) *rou
 		parent: rg.parent,
 	}
 
-	for _, opt := range append(rg.options, opts...) {
-		opt(route)
-	}
+	rg.apply(route, opts)
 
-	// Add the group's route to the router
-	rg.router.root.AddRoute(fullPath, route)
+	rg.router.add(fullPath, route)
 
 	return &routeGroup{
 		router:  rg.router,
@@ -244,12 +322,25 @@ func (rg *routeGroup) Handle(path string, view lv.View, opts ...routeOption) *ro
 		parent: rg.parent,
 	}
 
-	for _, opt := range append(rg.options, opts...) {
+	rg.apply(route, opts)
+
+	rg.router.add(fullPath, route)
+	return route
+}
+
+// apply sets the group's options and then opts on route. The group's name
+// and layout aren't passed down, the group renders its own layout.
+func (rg *routeGroup) apply(route *route, opts []routeOption) {
+	for _, opt := range rg.options {
 		opt(route)
 	}
 
-	rg.router.root.AddRoute(fullPath, route)
-	return route
+	route.name = ""
+	route.layout = nil
+
+	for _, opt := range opts {
+		opt(route)
+	}
 }
 
 func (rg *routeGroup) combinePaths(base, new string) string {
@@ -257,12 +348,14 @@ func (rg *routeGroup) combinePaths(base, new string) string {
 	return path.Join(base, new)
 }
 
-func findSession(route *route) string {
+var defaultSession = &liveSession{}
+
+func findSession(route *route) *liveSession {
 	if route == nil {
-		return ""
+		return defaultSession
 	}
 
-	if route.session != "" {
+	if route.session != nil {
 		return route.session
 	}
 
@@ -1,6 +1,10 @@
 package router
 
 import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/sethpollack/go-live-view/html"
@@ -63,8 +67,8 @@ func TestGetRoute(t *testing.T) {
 			routes: []routes{
 				{path: "/test", lv: &testLive{name: "test"}},
 			},
-			expected:       "<div>test</div>",
-			expectedParams: params.Params{},
+			expected:      "<div>404 Not Found</div>",
+			expectedError: lv.NotFoundError,
 		},
 		{
 			name: "simple routes",
@@ -398,3 +402,277 @@ func createRoutes(rt handler, routes []routes) {
 		}
 	}
 }
+
+func TestSessionOnMount(t *testing.T) {
+	var calls []string
+	hook := func(name string) lv.OnMount {
+		return func(lv.Socket, params.Params, map[string]any) error {
+			calls = append(calls, name)
+			return nil
+		}
+	}
+
+	rt := NewRouter(testLayout)
+	rt.Handle("/", &testLive{name: "home"})
+	admin := rt.Group("/admin", &testLive{name: "admin"}, WithSession("admin", hook("auth"), hook("audit")))
+	admin.Handle("/users", &testLive{name: "users"})
+	admin.Handle("/public", &testLive{name: "public"}, WithSession("public"))
+
+	for path, expected := range map[string][]string{
+		"/":             nil,
+		"/admin":        {"auth", "audit"},
+		"/admin/users":  {"auth", "audit"},
+		"/admin/public": nil,
+	} {
+		calls = nil
+
+		route, err := rt.GetRoute(path)
+		assert.NoError(t, err)
+
+		for _, h := range route.GetOnMount() {
+			assert.NoError(t, h(nil, nil, nil))
+		}
+		assert.Equal(t, expected, calls, path)
+	}
+}
+
+func TestGetRouteConcurrent(t *testing.T) {
+	rt := NewRouter(testLayout)
+	nested := rt.Group("/nested", &testLive{name: "nested"})
+	nested.Handle("/:id", &testLive{name: "show"}, WithParams(params.Params{"tab": "info"}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := strconv.Itoa(i)
+			path := "/nested/" + id
+			tab := "info"
+			if i%2 == 0 {
+				path += "?tab=edit"
+				tab = "edit"
+			}
+
+			for j := 0; j < 100; j++ {
+				route, err := rt.GetRoute(path)
+				assert.NoError(t, err)
+
+				p := route.GetParams()
+				assert.Equal(t, id, p.String("id"))
+				assert.Equal(t, tab, p.String("tab"))
+
+				// callers can't change the match, or the route behind it.
+				p.Set("id", "changed")
+				assert.Equal(t, id, route.GetParams().String("id"))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	route, err := rt.GetRoute("/nested/1")
+	assert.NoError(t, err)
+	assert.Equal(t, params.Params{"id": "1", "tab": "info"}, route.GetParams())
+}
+
+func pathRouter() *router {
+	rt := NewRouter(testLayout)
+	rt.Handle("/", &testLive{name: "home"}, WithName("home"))
+	nested := rt.Group("/nested", &testLive{name: "nested"}, WithName("nested"))
+	nested.Handle("/:id", &testLive{name: "show"}, WithName("nested_show"))
+	nested.Handle("/:id/edit", &testLive{name: "edit"}, WithName("nested.edit"))
+	nested.Handle("/:id/:type", &testLive{name: "type"})
+	rt.Handle("/files/*", &testLive{name: "files"}, WithName("files"))
+	rt.Handle("/docs/*path", &testLive{name: "docs"}, WithName("docs"))
+	rt.Handle("/posts/:id<int>", &testLive{name: "post"}, WithName("post"))
+	rt.Handle("/tags/:tag~r[a-z]+", &testLive{name: "tag"}, WithName("tag"))
+	rt.Handle("/users/:id<uuid>", &testLive{name: "user"}, WithName("user"))
+	rt.Handle("/prices/:amount<float>", &testLive{name: "price"}, WithName("price"))
+	rt.Handle("/flags/:on<bool>", &testLive{name: "flag"}, WithName("flag"))
+	return rt
+}
+
+func TestPath(t *testing.T) {
+	tt := []struct {
+		name     string
+		route    string
+		params   params.Params
+		expected string
+		err      string
+	}{
+		{name: "static", route: "home", expected: "/"},
+		{name: "group", route: "nested", expected: "/nested"},
+		{name: "param", route: "nested_show", params: params.Params{"id": 1}, expected: "/nested/1"},
+		{name: "escaped", route: "nested.edit", params: params.Params{"id": "a b?c"}, expected: "/nested/a%20b%3Fc/edit"},
+		{
+			name:     "query",
+			route:    "nested_show",
+			params:   params.Params{"id": "1", "tab": "a&b", "tags": []string{"x", "y"}},
+			expected: "/nested/1?tab=a%26b&tags=x&tags=y",
+		},
+		{name: "catch all", route: "files", params: params.Params{"*": "readme"}, expected: "/files/readme"},
+		{name: "named catch all", route: "docs", params: params.Params{"path": "guides/a b"}, expected: "/docs/guides/a%20b"},
+		{name: "empty catch all", route: "docs", expected: "/docs/"},
+		{name: "typed", route: "post", params: params.Params{"id": 5}, expected: "/posts/5"},
+		{name: "typed mismatch", route: "post", params: params.Params{"id": "new"}, err: "path /posts/:id<int>: param id doesn't match :id<int>"},
+		{name: "regex", route: "tag", params: params.Params{"tag": "go"}, expected: "/tags/go"},
+		{name: "regex mismatch", route: "tag", params: params.Params{"tag": "Go"}, err: "path /tags/:tag~r[a-z]+: param tag doesn't match :tag~r[a-z]+"},
+		{name: "missing param", route: "nested_show", err: "path /nested/:id: missing param id"},
+		{name: "empty param", route: "nested_show", params: params.Params{"id": ""}, err: "path /nested/:id: missing param id"},
+		{name: "unknown route", route: "nope", err: "route nope not found"},
+	}
+
+	rt := pathRouter()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			path, err := rt.Path(tc.route, tc.params)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, path)
+
+			// built paths route back to the named route
+			route, err := rt.GetRoute(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.route, route.(*match).route.name)
+		})
+	}
+
+	assert.Panics(t, func() {
+		rt.Handle("/other", &testLive{}, WithName("home"))
+	})
+	assert.PanicsWithValue(t, "router: route /nested/:name conflicts with /nested/:id", func() {
+		rt.Handle("/nested/:name", &testLive{})
+	})
+}
+
+func TestGeneratePaths(t *testing.T) {
+	var b strings.Builder
+	assert.NoError(t, GeneratePaths(&b, "paths", pathRouter()))
+
+	expected, err := os.ReadFile("testdata/paths.go.golden")
+	assert.NoError(t, err)
+	assert.Equal(t, string(expected), b.String())
+}
+
+func TestLayouts(t *testing.T) {
+	wrapIn := func(name string) func(rend.Node) rend.Node {
+		return func(n rend.Node) rend.Node {
+			return html.Section(html.ClassAttr(name), n)
+		}
+	}
+
+	rootIn := func(name string) func(...rend.Node) rend.Node {
+		return func(n ...rend.Node) rend.Node {
+			return html.Main(append([]rend.Node{html.ClassAttr(name)}, n...)...)
+		}
+	}
+
+	rt := NewRouter(rootIn("default"))
+	rt.Handle("/", &testLive{name: "home"})
+	rt.Handle("/about", &testLive{name: "about"}, WithLayout(wrapIn("marketing")))
+
+	admin := rt.Group("/admin", &testLive{name: "admin"},
+		WithRootLayout(rootIn("admin")),
+		WithLayout(wrapIn("admin")),
+	)
+	admin.Handle("/users", &testLive{name: "users"}, WithLayout(wrapIn("users")))
+	admin.Handle("/settings", &testLive{name: "settings"})
+
+	tt := []struct {
+		path     string
+		expected string
+		root     string
+	}{
+		{
+			path:     "/",
+			expected: `<div>home</div>`,
+			root:     `<main class="default"></main>`,
+		},
+		{
+			path:     "/about",
+			expected: `<section class="marketing"><div>about</div></section>`,
+			root:     `<main class="default"></main>`,
+		},
+		{
+			path:     "/admin/users",
+			expected: `<section class="admin"><div>admin<section class="users"><div>users</div></section></div></section>`,
+			root:     `<main class="admin"></main>`,
+		},
+		{
+			path:     "/admin/settings",
+			expected: `<section class="admin"><div>admin<div>settings</div></div></section>`,
+			root:     `<main class="admin"></main>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.path, func(t *testing.T) {
+			route, err := rt.GetRoute(tc.path)
+			assert.NoError(t, err)
+
+			node, err := route.GetView().Render(nil)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, rend.RenderString(node))
+			assert.Equal(t, tc.root, rend.RenderString(route.GetLayout()()))
+		})
+	}
+
+	get := func(path string) lv.Route {
+		route, err := rt.GetRoute(path)
+		assert.NoError(t, err)
+		return route
+	}
+
+	assert.True(t, rt.Routable(get("/"), get("/about")))
+	assert.True(t, rt.Routable(get("/admin/users"), get("/admin/settings")))
+	assert.False(t, rt.Routable(get("/about"), get("/admin/users")))
+}
+
+type replyLive struct {
+	testLive
+	events []string
+	reply  any
+}
+
+func (l *replyLive) EventReply(s lv.Socket, event string, p params.Params) (any, error) {
+	l.events = append(l.events, event)
+	return l.reply, nil
+}
+
+type eventLive struct {
+	testLive
+	events []string
+}
+
+func (l *eventLive) Event(s lv.Socket, event string, p params.Params) error {
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestEventReply(t *testing.T) {
+	r := NewRouter(testLayout)
+
+	parent := &replyLive{reply: "parent"}
+	middle := &eventLive{}
+	child := &replyLive{reply: "child"}
+
+	r.Group("/a", parent).Group("/b", middle).Handle("/c", child)
+
+	route, err := r.GetRoute("/a/b/c")
+	assert.NoError(t, err)
+
+	reply, err := lv.TryEventReply(route.GetView(), nil, "ping", nil)
+	assert.NoError(t, err)
+
+	// every view gets the event, and the innermost reply is sent.
+	assert.Equal(t, "child", reply)
+	assert.Equal(t, []string{"ping"}, parent.events)
+	assert.Equal(t, []string{"ping"}, middle.events)
+	assert.Equal(t, []string{"ping"}, child.events)
+}
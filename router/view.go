@@ -15,7 +15,10 @@ var _ interface {
 	lv.Unmounter
 	lv.Patcher
 	lv.EventHandler
+	lv.EventReplier
+	lv.InfoHandler
 	lv.Uploader
+	lv.Titler
 } = &wrapper{}
 
 type wrapper struct {
@@ -101,13 +104,51 @@ func (v *wrapper) Event(s lv.Socket, e string, p params.Params) error {
 	})
 }
 
-func (v *wrapper) Render(rend.Node) (node rend.Node, err error) {
+// EventReply returns the reply of the innermost view that has one.
+func (v *wrapper) EventReply(s lv.Socket, e string, p params.Params) (reply any, err error) {
 	err = walk(v.route, func(route *route) error {
-		node, err = route.view.Render(node)
+		r, err := lv.TryEventReply(route.view, s, e, p)
+		if reply == nil {
+			reply = r
+		}
 		return err
 	})
 
-	return node, err
+	return reply, err
+}
+
+func (v *wrapper) Info(s lv.Socket, msg any) error {
+	return walk(v.route, func(route *route) error {
+		return lv.TryInfo(route.view, s, msg)
+	})
+}
+
+func (v *wrapper) Render(rend.Node) (node rend.Node, err error) {
+	for route := v.route; route != nil; route = route.parent {
+		if route.view != nil {
+			node, err = route.view.Render(node)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if route.layout != nil {
+			node = route.layout(node)
+		}
+	}
+
+	return node, nil
+}
+
+// Title returns the title of the innermost view that has one.
+func (v *wrapper) Title() string {
+	for route := v.route; route != nil; route = route.parent {
+		if title := lv.TryTitle(route.view); title != "" {
+			return title
+		}
+	}
+
+	return ""
 }
 
 func (v *wrapper) Uploads() *uploads.Uploads {
@@ -126,13 +167,17 @@ func (v *wrapper) Uploads() *uploads.Uploads {
 	return u
 }
 
+// walk calls f for route and its parents, skipping groups without a view.
 func walk(route *route, f func(*route) error) error {
-	for route != nil {
+	for ; route != nil; route = route.parent {
+		if route.view == nil {
+			continue
+		}
+
 		err := f(route)
 		if err != nil {
 			return err
 		}
-		route = route.parent
 	}
 
 	return nil
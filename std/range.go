@@ -9,9 +9,11 @@ import (
 
 type mapRange[T any] struct {
 	arr []T
+	key func(T) any
 	f   func(T) rend.Node
 }
 
+// Range renders f for each item. Items are diffed by position.
 func Range[T any](arr []T, f func(T) rend.Node) rend.Node {
 	return &mapRange[T]{
 		arr: arr,
@@ -19,6 +21,16 @@ func Range[T any](arr []T, f func(T) rend.Node) rend.Node {
 	}
 }
 
+// RangeKeyed renders f for each item, diffing items by key so inserts,
+// removals and moves only send the entries that changed.
+func RangeKeyed[T any, K comparable](arr []T, key func(T) K, f func(T) rend.Node) rend.Node {
+	return &mapRange[T]{
+		arr: arr,
+		key: func(v T) any { return key(v) },
+		f:   f,
+	}
+}
+
 func (c *mapRange[T]) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Builder) error {
 	if len(c.arr) <= 0 {
 		return nil
@@ -50,11 +62,12 @@ func (c *mapRange[T]) Render(diff bool, root *rend.Root, t *rend.Rend, b *string
 	}
 
 	if staticsMatch {
-		t.AddDynamic(&rend.Comprehension{
-			Static:      rends[0].Static,
-			Fingerprint: rends[0].Fingerprint,
-			Dynamics:    copyDynamics(rends),
-		})
+		t.AddDynamic(rend.NewComprehension(
+			rends[0].Static,
+			rends[0].Fingerprint,
+			copyDynamics(rends),
+			c.keys(),
+		))
 		t.AddStatic(b.String())
 		b.Reset()
 	} else {
@@ -68,6 +81,19 @@ func (c *mapRange[T]) Render(diff bool, root *rend.Root, t *rend.Rend, b *string
 	return nil
 }
 
+func (c *mapRange[T]) keys() []any {
+	if c.key == nil {
+		return nil
+	}
+
+	keys := make([]any, 0, len(c.arr))
+	for _, d := range c.arr {
+		keys = append(keys, c.key(d))
+	}
+
+	return keys
+}
+
 func copyDynamics(d []*rend.Rend) [][]any {
 	copy := [][]any{}
 
@@ -2,6 +2,7 @@ package std
 
 import (
 	"flag"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -9,6 +10,7 @@ import (
 	"github.com/sethpollack/go-live-view/html"
 	"github.com/sethpollack/go-live-view/rend"
 
+	"github.com/go-json-experiment/json"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -16,6 +18,7 @@ var (
 	update        = flag.Bool("update", false, "update .json files")
 	dynamicText   = "dynamic text"
 	dynamicNumber = 456
+	xssPayload    = `"><script>alert('xss')</script>`
 )
 
 func TestNode(t *testing.T) {
@@ -253,6 +256,18 @@ func TestNode(t *testing.T) {
 						&dynamicNumber,
 					),
 				},
+				{
+					name: "escaped",
+					node: Text(xssPayload),
+				},
+				{
+					name: "escaped dynamic",
+					node: Text(&xssPayload),
+				},
+				{
+					name: "escaped dynamic with format",
+					node: Textf("Hello %s", &xssPayload),
+				},
 			},
 		},
 	}
@@ -274,6 +289,138 @@ func TestNode(t *testing.T) {
 	}
 }
 
+func TestEscape(t *testing.T) {
+	tt := []struct {
+		name     string
+		node     rend.Node
+		expected string
+		escaped  bool
+	}{
+		{
+			name:     "text",
+			node:     html.Div(Text(xssPayload)),
+			expected: `<div>"&gt;&lt;script&gt;alert('xss')&lt;/script&gt;</div>`,
+			escaped:  true,
+		},
+		{
+			name:     "dynamic text",
+			node:     html.Div(Text(&xssPayload)),
+			expected: `<div>"&gt;&lt;script&gt;alert('xss')&lt;/script&gt;</div>`,
+			escaped:  true,
+		},
+		{
+			name:     "text with format",
+			node:     html.Div(Textf("<b>%s</b>", &xssPayload)),
+			expected: `<div>&lt;b&gt;"&gt;&lt;script&gt;alert('xss')&lt;/script&gt;&lt;/b&gt;</div>`,
+			escaped:  true,
+		},
+		{
+			name:     "comprehension",
+			node:     html.Ul(Range([]string{xssPayload}, func(s string) rend.Node { return html.Li(Text(&s)) })),
+			expected: `<ul><li>"&gt;&lt;script&gt;alert('xss')&lt;/script&gt;</li></ul>`,
+			escaped:  true,
+		},
+		{
+			name:     "raw is not escaped",
+			node:     html.Div(Raw(xssPayload)),
+			expected: `<div>"><script>alert('xss')</script></div>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, rend.RenderString(tc.node))
+
+			if tc.escaped {
+				assert.NotContains(t, rend.RenderJSON(tc.node), "<script>")
+			}
+		})
+	}
+}
+
+type benchRow struct {
+	ID    int
+	Name  string
+	Email string
+}
+
+// BenchmarkRangeDiff compares the payload of re-sending every entry, as
+// comprehensions were diffed before, with the keyed diff.
+func BenchmarkRangeDiff(b *testing.B) {
+	rows := make([]benchRow, 1000)
+	for i := range rows {
+		rows[i] = benchRow{
+			ID:    i,
+			Name:  fmt.Sprintf("user %d", i),
+			Email: fmt.Sprintf("user%d@example.com", i),
+		}
+	}
+
+	table := func(rows []benchRow) rend.Node {
+		return html.Table(
+			RangeKeyed(rows, func(r benchRow) int { return r.ID }, func(r benchRow) rend.Node {
+				return html.Tr(
+					html.Td(Text(&r.Name)),
+					html.Td(Text(&r.Email)),
+				)
+			}),
+		)
+	}
+
+	scenarios := []struct {
+		name string
+		next func([]benchRow) []benchRow
+	}{
+		{
+			name: "update one row",
+			next: func(rows []benchRow) []benchRow {
+				next := append([]benchRow{}, rows...)
+				next[500].Name = "changed"
+				return next
+			},
+		},
+		{
+			name: "prepend row",
+			next: func(rows []benchRow) []benchRow {
+				return append([]benchRow{{ID: -1, Name: "new", Email: "new@example.com"}}, rows...)
+			},
+		},
+		{
+			name: "remove row",
+			next: func(rows []benchRow) []benchRow {
+				return append(append([]benchRow{}, rows[:500]...), rows[501:]...)
+			},
+		},
+	}
+
+	for _, sc := range scenarios {
+		oldTree := rend.RenderTree(table(rows))
+		newTree := rend.RenderTree(table(sc.next(rows)))
+
+		b.Run(sc.name+"/before", func(b *testing.B) {
+			comp := newTree.Rend.Dynamic["0"].(*rend.Comprehension)
+
+			var payload []byte
+			for i := 0; i < b.N; i++ {
+				payload, _ = json.Marshal(map[string]any{
+					"0": map[string]any{"d": comp.Dynamics},
+				})
+			}
+
+			b.ReportMetric(float64(len(payload)), "payload-bytes")
+		})
+
+		b.Run(sc.name+"/after", func(b *testing.B) {
+			var payload []byte
+			for i := 0; i < b.N; i++ {
+				payload, _ = json.Marshal(oldTree.Diff(newTree), json.DefaultOptionsV2())
+			}
+
+			b.ReportMetric(float64(len(payload)), "payload-bytes")
+		})
+	}
+}
+
 func actualValue(t *testing.T, path string, actual string, update bool) string {
 	t.Helper()
 
@@ -49,9 +49,10 @@ func (s *stream) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Bui
 			stream = append(stream, true)
 		}
 
-		t.AddDynamic(&rend.Comprehension{
-			Stream: stream,
-		})
+		c := rend.NewComprehension(nil, "", nil, nil)
+		c.Stream = stream
+
+		t.AddDynamic(c)
 		t.AddStatic(b.String())
 		b.Reset()
 		return nil
@@ -92,12 +93,15 @@ func (s *stream) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Bui
 			stream = append(stream, true)
 		}
 
-		t.AddDynamic(&rend.Comprehension{
-			Static:      rends[0].Static,
-			Fingerprint: rends[0].Fingerprint,
-			Dynamics:    copyDynamics(rends),
-			Stream:      stream,
-		})
+		c := rend.NewComprehension(
+			rends[0].Static,
+			rends[0].Fingerprint,
+			copyDynamics(rends),
+			nil,
+		)
+		c.Stream = stream
+
+		t.AddDynamic(c)
 		t.AddStatic(b.String())
 		b.Reset()
 	} else {
@@ -52,15 +52,17 @@ func Textf(format string, a ...any) rend.Node {
 }
 
 func (text *text) Render(diff bool, root *rend.Root, t *rend.Rend, b *strings.Builder) error {
+	escaped := rend.EscapeText(text.text)
+
 	if diff {
 		if text.dynamic {
-			t.AddDynamic(text.text)
+			t.AddDynamic(escaped)
 			t.AddStatic(b.String())
 			b.Reset()
 			return nil
 		}
 	}
 
-	_, err := b.Write([]byte(text.text))
+	_, err := b.Write([]byte(escaped))
 	return err
 }